@@ -0,0 +1,320 @@
+package exchange
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	parser "github.com/Diamon0/rns-babel/Parser"
+	"gopkg.in/yaml.v3"
+)
+
+// Bundles are a single JSON or YAML document per language, for translators who'd rather edit text than use tools:
+// sheet -> key -> the reference text, the translation, and the level or dialogue fields to go by.
+// Sheets and keys are maps, so both formats come out sorted and diff cleanly.
+
+type BundleFormat string
+
+const (
+	BundleJSON BundleFormat = "json"
+	BundleYAML BundleFormat = "yaml"
+)
+
+// Format of a bundle file, from its extension
+func BundleFormatOf(path string) (BundleFormat, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return BundleJSON, nil
+	case ".yaml", ".yml":
+		return BundleYAML, nil
+	}
+
+	return "", fmt.Errorf("%v is neither a JSON nor a YAML file", path)
+}
+
+// A row of a bundle
+type BundleEntry struct {
+	// Text in the reference language, only there to translate from
+	Source string `json:"source" yaml:"source"`
+
+	// Text in the bundle's language, the only thing that is imported
+	Target string `json:"target" yaml:"target"`
+
+	// Level of key-level sheets
+	Level *int `json:"level,omitempty" yaml:"level,omitempty"`
+
+	// Fields of dialogue lines (type, flag and expression)
+	Context map[string]string `json:"context,omitempty" yaml:"context,omitempty"`
+}
+
+type Bundle struct {
+	// Names as in LanguageEnable.csv
+	Language  string `json:"language" yaml:"language"`
+	Reference string `json:"reference" yaml:"reference"`
+
+	// Sheet ID -> key -> entry
+	Sheets map[string]map[string]BundleEntry `json:"sheets" yaml:"sheets"`
+}
+
+// How bundles are exported
+type BundleOptions struct {
+	// Language the sources come from.
+	// If empty, the first language of LanguageEnable.csv is used.
+	Reference string
+}
+
+// The bundle of a language. Entries without reference text are left out,
+// and entries without a translation have an empty target.
+func BuildBundle(lfs parser.LanguageFiles, lang string, opts BundleOptions) (*Bundle, error) {
+	if err := checkLanguage(&lfs, lang); err != nil {
+		return nil, err
+	}
+	reference, err := referenceLanguage(&lfs, opts.Reference)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &Bundle{
+		Language:  lang,
+		Reference: reference,
+		Sheets:    make(map[string]map[string]BundleEntry),
+	}
+
+	for _, entry := range entries(&lfs) {
+		source, _ := entry.Get(reference)
+		if source == "" {
+			continue
+		}
+
+		bundleEntry := BundleEntry{Source: source}
+		bundleEntry.Target, _ = entry.Get(lang)
+		if entry.KeyLevel != nil {
+			level := entry.KeyLevel.Level
+			bundleEntry.Level = &level
+		}
+		if entry.Dialogue != nil {
+			bundleEntry.Context = make(map[string]string)
+			for _, field := range entryFields(&entry) {
+				bundleEntry.Context[strings.ToLower(field[0])] = field[1]
+			}
+		}
+
+		if bundle.Sheets[entry.Sheet] == nil {
+			bundle.Sheets[entry.Sheet] = make(map[string]BundleEntry)
+		}
+		bundle.Sheets[entry.Sheet][entry.Key] = bundleEntry
+	}
+
+	return bundle, nil
+}
+
+// Writes the bundle in a format
+func (b *Bundle) Write(w io.Writer, format BundleFormat) error {
+	switch format {
+	case BundleJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "\t")
+		return encoder.Encode(b)
+	case BundleYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(b); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+
+	return fmt.Errorf("Unknown bundle format %v", format)
+}
+
+// Reads a bundle in a format
+func ReadBundle(r io.Reader, format BundleFormat) (*Bundle, error) {
+	var bundle Bundle
+
+	switch format {
+	case BundleJSON:
+		decoder := json.NewDecoder(r)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&bundle); err != nil {
+			return nil, err
+		}
+	case BundleYAML:
+		decoder := yaml.NewDecoder(r)
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&bundle); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("Unknown bundle format %v", format)
+	}
+
+	return &bundle, nil
+}
+
+// Writes a bundle for every language but the reference one into a folder, as <language code>.json or .yaml
+func ExportBundleFiles(dir string, lfs parser.LanguageFiles, format BundleFormat, opts BundleOptions) error {
+	reference, err := referenceLanguage(&lfs, opts.Reference)
+	if err != nil {
+		return err
+	}
+	opts.Reference = reference
+
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for _, lang := range lfs.Languages.Names() {
+		if lang == reference {
+			continue
+		}
+
+		bundle, err := BuildBundle(lfs, lang, opts)
+		if err != nil {
+			return err
+		}
+		code, err := LanguageCode(lang)
+		if err != nil {
+			return err
+		}
+		err = writeFile(dir, code+"."+string(format), func(w io.Writer) error {
+			return bundle.Write(w, format)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// How a bundle is imported
+type BundleImportOptions struct {
+	// Also import entries whose source text changed since the bundle was exported.
+	// Dialogue lines never are, their key is their row and may belong to another line now.
+	IncludeChanged bool
+
+	// Only report what would change, don't change or save anything
+	DryRun bool
+}
+
+// What importing a bundle did
+type BundleImportReport struct {
+	Language string
+
+	// Contexts of the entries whose translation changed
+	Updated []string
+
+	// Entries that already had the same translation
+	Unchanged int
+
+	// Entries with an empty target, which are left alone
+	Untranslated int
+
+	// Contexts of the entries whose sheet or key doesn't exist anymore
+	Orphans []string
+
+	// Contexts of the entries whose source isn't the current source text anymore, only imported with IncludeChanged (dialogue lines never are)
+	SourceChanged []string
+
+	// Sheets that were saved
+	Saved []string
+}
+
+// Writes the targets of a bundle into the sheets and saves the ones that changed through their Update.
+// Entries are gone through sorted by sheet and key, so reports come out the same every time.
+func ImportBundle(lfs *parser.LanguageFiles, bundle *Bundle, opts BundleImportOptions) (*BundleImportReport, error) {
+	lang, ok := languageByCode(lfs, bundle.Language)
+	if !ok {
+		return nil, fmt.Errorf("Language %v is not in LanguageEnable.csv", bundle.Language)
+	}
+	reference, err := referenceLanguage(lfs, bundle.Reference)
+	if err != nil {
+		return nil, err
+	}
+	if reference == lang {
+		return nil, fmt.Errorf("Refusing to import into the reference language %v", lang)
+	}
+
+	idx := parser.NewIndex(lfs)
+	report := &BundleImportReport{Language: lang}
+
+	for _, sheet := range sortedKeys(bundle.Sheets) {
+		for _, key := range sortedKeys(bundle.Sheets[sheet]) {
+			bundleEntry := bundle.Sheets[sheet][key]
+
+			target := idx.Entry(sheet, key)
+			if target == nil {
+				report.Orphans = append(report.Orphans, Context(sheet, key))
+				continue
+			}
+
+			if bundleEntry.Target == "" {
+				report.Untranslated++
+				continue
+			}
+
+			if source, _ := target.Get(reference); source != bundleEntry.Source {
+				report.SourceChanged = append(report.SourceChanged, Context(sheet, key))
+				if !opts.IncludeChanged || target.Dialogue != nil {
+					continue
+				}
+			}
+
+			if current, _ := target.Get(lang); current == bundleEntry.Target {
+				report.Unchanged++
+				continue
+			}
+
+			report.Updated = append(report.Updated, Context(sheet, key))
+			if opts.DryRun {
+				continue
+			}
+			if err = idx.Set(sheet, key, lang, bundleEntry.Target); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	report.Saved = idx.Dirty()
+	if err = idx.Save(); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// Reads a bundle, in the format its extension says, and imports it
+func ImportBundleFile(path string, lfs *parser.LanguageFiles, opts BundleImportOptions) (*BundleImportReport, error) {
+	format, err := BundleFormatOf(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	bundle, err := ReadBundle(file, format)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+
+	return ImportBundle(lfs, bundle, opts)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
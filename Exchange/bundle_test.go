@@ -0,0 +1,203 @@
+package exchange
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildBundle(t *testing.T) {
+	t.Log("Testing bundle export...")
+
+	_, gameFiles := loadTestGame(t)
+
+	bundle, err := BuildBundle(gameFiles, "Japanese", BundleOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bundle.Language != "Japanese" || bundle.Reference != "English" || len(bundle.Sheets) != 3 {
+		t.Fatalf("Unexpected bundle %+v", bundle)
+	}
+
+	items := bundle.Sheets["Data/Names_Item.csv"]
+	if len(items) != 2 || items["Item_b"].Source != `Big "Sword"` || items["Item_b"].Target != "" {
+		t.Errorf("Expected Item_a and an untranslated Item_b, got %+v", items)
+	}
+	if items["Item_a"].Level == nil || *items["Item_a"].Level != 1 || items["Item_a"].Context != nil {
+		t.Errorf("Expected Item_a to have level 1, got %+v", items["Item_a"])
+	}
+	frog := bundle.Sheets["Dialog/frog.csv"]["3"]
+	if !reflect.DeepEqual(frog.Context, map[string]string{"type": "1", "flag": "flag_frog", "expression": "smile"}) || frog.Level != nil {
+		t.Errorf("Expected the dialogue fields as context, got %+v", frog)
+	}
+	t.Log("Entries are right...")
+
+	for _, format := range []BundleFormat{BundleJSON, BundleYAML} {
+		var out bytes.Buffer
+		if err = bundle.Write(&out, format); err != nil {
+			t.Fatal(err)
+		}
+		written := out.String()
+
+		read, err := ReadBundle(&out, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(read, bundle) {
+			t.Errorf("Expected %+v back from %v, got %+v", bundle, format, read)
+		}
+
+		// Same bundle, same bytes
+		out.Reset()
+		if err = read.Write(&out, format); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != written {
+			t.Errorf("Writing %v twice gave different results:\n%v\n%v", format, written, out.String())
+		}
+
+		if strings.Index(written, "Data/Names_Item.csv") > strings.Index(written, "Data/Strings_Menu.csv") {
+			t.Errorf("Sheets aren't sorted in %v:\n%v", format, written)
+		}
+	}
+	t.Log("JSON and YAML read back the same...")
+
+	if _, err = ReadBundle(strings.NewReader(`{"language": "Japanese", "sheet": {}}`), BundleJSON); err == nil {
+		t.Error("Read a bundle with a misspelled field")
+	}
+	if _, err = BundleFormatOf("ja.po"); err == nil {
+		t.Error("Took a PO file for a bundle")
+	}
+
+	t.Log("Bundle export Passed!")
+}
+
+const testImportBundle = `language: ja
+reference: English
+sheets:
+  Data/Names_Item.csv:
+    Item_a:
+      source: Apple
+      target: りんご
+    Item_b:
+      source: Big "Sword"
+      target: 大きな剣
+    Item_c:
+      source: Nothing
+      target: ""
+    Item_gone:
+      source: Gone
+      target: 消えた
+  Data/Strings_Menu.csv:
+    Menu_gold:
+      source: "{0} gold left"
+      target: 残り{0}ゴールド
+  Dialog/frog.csv:
+    "2":
+      source: Ribbit!
+      target: ケロケロ!
+    "3":
+      source: "Bye!\r\nSee you."
+      target: "じゃあね!\r\nまたね。"
+`
+
+func TestImportBundle(t *testing.T) {
+	t.Log("Testing ImportBundle...")
+
+	gamePath, gameFiles := loadTestGame(t)
+	bundle, err := ReadBundle(strings.NewReader(testImportBundle), BundleYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := ImportBundle(&gameFiles, bundle, BundleImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Updated) != 2 || len(report.Saved) != 0 {
+		t.Errorf("Dry run should only report, got %+v", report)
+	}
+
+	report, err = ImportBundle(&gameFiles, bundle, BundleImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Language != "Japanese" {
+		t.Errorf("Expected ja to be Japanese, got %v", report.Language)
+	}
+	if !reflect.DeepEqual(report.Updated, []string{"Data/Names_Item.csv|Item_b", "Dialog/frog.csv|3"}) {
+		t.Errorf("Unexpected updates %v", report.Updated)
+	}
+	if report.Unchanged != 1 || report.Untranslated != 1 {
+		t.Errorf("Expected 1 unchanged and 1 untranslated entry, got %d and %d", report.Unchanged, report.Untranslated)
+	}
+	if !reflect.DeepEqual(report.Orphans, []string{"Data/Names_Item.csv|Item_gone"}) {
+		t.Errorf("Expected Item_gone to be an orphan, got %v", report.Orphans)
+	}
+	if !reflect.DeepEqual(report.SourceChanged, []string{"Data/Strings_Menu.csv|Menu_gold", "Dialog/frog.csv|2"}) {
+		t.Errorf("Expected Menu_gold and the first frog line to have changed, got %v", report.SourceChanged)
+	}
+	if !reflect.DeepEqual(report.Saved, []string{"Data/Names_Item.csv", "Dialog/frog.csv"}) {
+		t.Errorf("Unexpected saved sheets %v", report.Saved)
+	}
+	t.Log("Report is right...")
+
+	names, err := os.ReadFile(gamePath + "/Data/Names_Item.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(names), "Item_b,2,\"Big \"\"Sword\"\"\",大きな剣,Großes Schwert\r\n") {
+		t.Errorf("Names_Item wasn't updated:\n%s", names)
+	}
+
+	report, err = ImportBundle(&gameFiles, bundle, BundleImportOptions{IncludeChanged: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.Updated, []string{"Data/Strings_Menu.csv|Menu_gold"}) {
+		t.Errorf("Expected only Menu_gold to be imported with IncludeChanged, dialogue lines never are, got %v", report.Updated)
+	}
+
+	t.Log("ImportBundle Passed!")
+}
+
+func TestExportBundleFiles(t *testing.T) {
+	t.Log("Testing ExportBundleFiles...")
+
+	_, gameFiles := loadTestGame(t)
+	dir := t.TempDir()
+
+	if err := ExportBundleFiles(dir, gameFiles, BundleJSON, BundleOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExportBundleFiles(dir, gameFiles, BundleYAML, BundleOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = filepath.Base(file)
+	}
+	if strings.Join(names, " ") != "de.json de.yaml ja.json ja.yaml" {
+		t.Errorf("Expected a JSON and a YAML file for de and ja, got %v", names)
+	}
+
+	for _, name := range names {
+		report, err := ImportBundleFile(filepath.Join(dir, name), &gameFiles, BundleImportOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Updated) != 0 || len(report.Saved) != 0 {
+			t.Errorf("Importing an untouched %v shouldn't change anything, got %+v", name, report)
+		}
+	}
+
+	t.Log("ExportBundleFiles Passed!")
+}
@@ -0,0 +1,199 @@
+package exchange
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	parser "github.com/Diamon0/rns-babel/Parser"
+)
+
+// Turning the game's translations into the formats translators and their tools use, and reading them back.
+// Everything here works on parser.LanguageFiles; imports change the sheets in memory
+// and save the ones that changed themselves, through parser.Index.Save, unless they are only a dry run.
+//
+// Dialogue lines have no key, so their key is their row number (see Context).
+// Adding or removing a line shifts the key of every line after it, so a translation exported before that
+// would go into the wrong line. Every import checks a dialogue line's source text is still the one it was exported with,
+// and leaves the line alone if it isn't, whatever the options say.
+// TMX files only ever go into the translation memory, never back into the game files.
+
+// Language codes for the names used in LanguageEnable.csv, which is what most tools expect.
+// Languages missing from here can't be exported to formats that need a code, add them first.
+var LanguageCodes map[string]string = map[string]string{
+	"English":    "en",
+	"Japanese":   "ja",
+	"Chinese":    "zh",
+	"Korean":     "ko",
+	"German":     "de",
+	"French":     "fr",
+	"Spanish":    "es",
+	"Italian":    "it",
+	"Portuguese": "pt",
+	"Russian":    "ru",
+	"Polish":     "pl",
+	"Turkish":    "tr",
+	"Ukrainian":  "uk",
+	"Dutch":      "nl",
+	"Swedish":    "sv",
+	"Czech":      "cs",
+	"Hungarian":  "hu",
+	"Thai":       "th",
+	"Vietnamese": "vi",
+	"Indonesian": "id",
+}
+
+// Language tags like ja, pt-BR or zh-Hant-TW
+var languageTag = regexp.MustCompile(`^[A-Za-z]{2,3}(?:-[A-Za-z0-9]{2,8})*$`)
+
+// BCP 47 code of a language. Names that already are a code are given back as they are,
+// anything else has to be in LanguageCodes.
+func LanguageCode(name string) (string, error) {
+	if code, ok := LanguageCodes[name]; ok {
+		return code, nil
+	}
+	if languageTag.MatchString(name) {
+		return name, nil
+	}
+
+	return "", fmt.Errorf("Language %v has no language code, add it to LanguageCodes", name)
+}
+
+// Name of the language with that code (or name) in LanguageEnable.csv, if there is one.
+// Codes with a region, like ja_JP or pt-BR, match the language without one if that's all there is.
+func languageByCode(lfs *parser.LanguageFiles, code string) (string, bool) {
+	for _, name := range lfs.Languages.Names() {
+		if nameCode, err := LanguageCode(name); name == code || (err == nil && strings.EqualFold(nameCode, code)) {
+			return name, true
+		}
+	}
+
+	if base, _, ok := strings.Cut(strings.ReplaceAll(code, "-", "_"), "_"); ok {
+		return languageByCode(lfs, base)
+	}
+
+	return "", false
+}
+
+// Works out the reference language, the first one in LanguageEnable.csv if none is given
+func referenceLanguage(lfs *parser.LanguageFiles, reference string) (string, error) {
+	names := lfs.Languages.Names()
+	if reference == "" {
+		if len(names) == 0 {
+			return "", errors.New("There are no languages to use as a reference")
+		}
+		return names[0], nil
+	}
+
+	for _, name := range names {
+		if name == reference {
+			return name, nil
+		}
+	}
+
+	return "", fmt.Errorf("Language %v does not exist", reference)
+}
+
+// Makes sure a language is in LanguageEnable.csv
+func checkLanguage(lfs *parser.LanguageFiles, lang string) error {
+	if lang == "" {
+		return errors.New("No language given")
+	}
+	if _, err := referenceLanguage(lfs, lang); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Separates the sheet from the key in a Context.
+// Sheet IDs never have one, keys might.
+const contextSeparator string = "|"
+
+// Where an entry comes from, as a single string: its sheet and key
+func Context(sheet, key string) string {
+	return sheet + contextSeparator + key
+}
+
+// The sheet and key of a Context
+func SplitContext(context string) (string, string, bool) {
+	return strings.Cut(context, contextSeparator)
+}
+
+// Every entry with text to translate, in load order.
+// If a key shows up twice in a sheet, only the first one is kept, like parser.Index does.
+func entries(lfs *parser.LanguageFiles) []parser.Entry {
+	all := lfs.Entries()
+	seen := make(map[string]bool)
+	unique := make([]parser.Entry, 0, len(all))
+
+	for _, entry := range all {
+		context := Context(entry.Sheet, entry.Key)
+		if seen[context] {
+			continue
+		}
+		seen[context] = true
+
+		unique = append(unique, entry)
+	}
+
+	return unique
+}
+
+// What a translator should know about an entry besides its text, as name and value pairs:
+// the level of names, descriptions and titles, and the type and flag of dialogue lines
+func entryNotes(entry *parser.Entry) [][2]string {
+	switch {
+	case entry.KeyLevel != nil:
+		return [][2]string{{"Level", fmt.Sprint(entry.KeyLevel.Level)}}
+	case entry.Dialogue != nil:
+		notes := [][2]string{{"Type", fmt.Sprint(entry.Dialogue.Type)}}
+		if entry.Dialogue.FlagScript != nil {
+			notes = append(notes, [2]string{"Flag", fmt.Sprint(entry.Dialogue.FlagScript)})
+		}
+		return notes
+	}
+
+	return nil
+}
+
+// entryNotes with the expression of dialogue lines too, for formats that have room for it
+func entryFields(entry *parser.Entry) [][2]string {
+	notes := entryNotes(entry)
+	if entry.Dialogue != nil && entry.Dialogue.ExpressionVar0 != nil {
+		notes = append(notes, [2]string{"Expression", fmt.Sprint(entry.Dialogue.ExpressionVar0)})
+	}
+
+	return notes
+}
+
+// Creates a file in dir and fills it with write
+func writeFile(dir, name string, write func(w io.Writer) error) error {
+	file, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err = write(file); err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
+// Fills a file with write like writeFile, but through parser.WriteFileAtomic,
+// so if anything fails, whatever was there before is left untouched
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	var data bytes.Buffer
+	if err := write(&data); err != nil {
+		return err
+	}
+
+	return parser.WriteFileAtomic(path, data.Bytes(), 0644)
+}
@@ -0,0 +1,52 @@
+package exchange
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	parser "github.com/Diamon0/rns-babel/Parser"
+)
+
+// Copies the test game into a temporary folder, keeps backups out of the way and parses it
+func loadTestGame(t *testing.T) (string, parser.LanguageFiles) {
+	t.Helper()
+
+	backupDir := parser.BackupDir
+	parser.BackupDir = t.TempDir()
+	parser.StartSnapshot()
+	t.Cleanup(func() {
+		parser.BackupDir = backupDir
+		parser.StartSnapshot()
+	})
+
+	gamePath := t.TempDir()
+	err := filepath.WalkDir("testdata/game", func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relative, err := filepath.Rel("testdata/game", path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(filepath.Join(gamePath, relative), 0755)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(gamePath, relative), data, 0644)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gameFiles, err := parser.ParseGameFiles(gamePath)
+	if err != nil {
+		t.Fatalf("Failed to parse game files with error:\n%v", err)
+	}
+
+	return gamePath, gameFiles
+}
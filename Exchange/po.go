@@ -0,0 +1,247 @@
+package exchange
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	parser "github.com/Diamon0/rns-babel/Parser"
+)
+
+// An entry of a gettext PO file
+type POEntry struct {
+	// Lines of "# " comments, written by translators
+	TranslatorComments []string
+
+	// Lines of "#." comments, written by us
+	ExtractedComments []string
+
+	// "#:" references, like Data/Names_Item.csv:3
+	References []string
+
+	// "#," flags, like fuzzy
+	Flags []string
+
+	// The msgid this one had before it changed, from "#| msgid"
+	PreviousID string
+
+	// Where the entry comes from, see Context
+	Context string
+	ID      string
+	Str     string
+
+	// Whether it was commented out with "#~", which tools do to entries that are gone
+	Obsolete bool
+}
+
+// Whether a translator marked the entry as needing another look
+func (e *POEntry) Fuzzy() bool {
+	for _, flag := range e.Flags {
+		if flag == "fuzzy" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// A gettext PO or POT file
+type POFile struct {
+	// The msgstr of the header entry, "Name: value" lines
+	Header string
+
+	Entries []POEntry
+}
+
+// A field of the header, or empty if it isn't there
+func (f *POFile) HeaderField(name string) string {
+	for _, line := range strings.Split(f.Header, "\n") {
+		field, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(field), name) {
+			return strings.TrimSpace(value)
+		}
+	}
+
+	return ""
+}
+
+// Header fields we write, so importing knows what a file holds even if it was renamed
+const (
+	poLanguageField  string = "X-RNS-Language"
+	poReferenceField string = "X-RNS-Reference"
+)
+
+// How PO files are exported
+type POOptions struct {
+	// Language the msgids come from.
+	// If empty, the first language of LanguageEnable.csv is used.
+	Reference string
+}
+
+// The POT template: every entry with text in the reference language, with empty translations.
+// Entries without reference text are left out, there is nothing to translate.
+func BuildPOT(lfs parser.LanguageFiles, opts POOptions) (*POFile, error) {
+	return buildPO(&lfs, "", opts)
+}
+
+// The PO file of a language: the template with the current translations filled in.
+// Cells that are empty are left as untranslated.
+func BuildPO(lfs parser.LanguageFiles, lang string, opts POOptions) (*POFile, error) {
+	if err := checkLanguage(&lfs, lang); err != nil {
+		return nil, err
+	}
+
+	return buildPO(&lfs, lang, opts)
+}
+
+func buildPO(lfs *parser.LanguageFiles, lang string, opts POOptions) (*POFile, error) {
+	reference, err := referenceLanguage(lfs, opts.Reference)
+	if err != nil {
+		return nil, err
+	}
+
+	header := []string{
+		"Project-Id-Version: RNS",
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"Content-Transfer-Encoding: 8bit",
+		"X-Generator: RNS-Babel",
+		poReferenceField + ": " + reference,
+	}
+	if lang != "" {
+		code, err := LanguageCode(lang)
+		if err != nil {
+			return nil, err
+		}
+		header = append(header, "Language: "+code, poLanguageField+": "+lang)
+	}
+
+	po := &POFile{Header: strings.Join(header, "\n") + "\n"}
+
+	for _, entry := range entries(lfs) {
+		source, _ := entry.Get(reference)
+		if source == "" {
+			continue
+		}
+
+		poEntry := POEntry{
+			References: []string{fmt.Sprintf("%v:%d", entry.Sheet, entry.Row+2)},
+			Context:    Context(entry.Sheet, entry.Key),
+			ID:         source,
+		}
+		for _, note := range entryNotes(&entry) {
+			poEntry.ExtractedComments = append(poEntry.ExtractedComments, note[0]+": "+note[1])
+		}
+		if lang != "" {
+			poEntry.Str, _ = entry.Get(lang)
+		}
+
+		po.Entries = append(po.Entries, poEntry)
+	}
+
+	return po, nil
+}
+
+// Writes the template and a PO file for every language but the reference one into a folder,
+// as template.pot and <language code>.po
+func ExportPOFiles(dir string, lfs parser.LanguageFiles, opts POOptions) error {
+	reference, err := referenceLanguage(&lfs, opts.Reference)
+	if err != nil {
+		return err
+	}
+	opts.Reference = reference
+
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	pot, err := BuildPOT(lfs, opts)
+	if err != nil {
+		return err
+	}
+	if err = writeFile(dir, "template.pot", pot.Write); err != nil {
+		return err
+	}
+
+	for _, lang := range lfs.Languages.Names() {
+		if lang == reference {
+			continue
+		}
+
+		po, err := BuildPO(lfs, lang, opts)
+		if err != nil {
+			return err
+		}
+		if err = writeFile(dir, po.HeaderField("Language")+".po", po.Write); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Writes the file in the usual PO layout
+func (f *POFile) Write(w io.Writer) error {
+	out := bufio.NewWriter(w)
+
+	writeString(out, "", "msgid", "")
+	writeString(out, "", "msgstr", f.Header)
+
+	for _, entry := range f.Entries {
+		out.WriteString("\n")
+
+		for _, comment := range entry.TranslatorComments {
+			out.WriteString(strings.TrimRight("# "+comment, " ") + "\n")
+		}
+		for _, comment := range entry.ExtractedComments {
+			out.WriteString("#. " + comment + "\n")
+		}
+		for _, reference := range entry.References {
+			out.WriteString("#: " + reference + "\n")
+		}
+		if len(entry.Flags) > 0 {
+			out.WriteString("#, " + strings.Join(entry.Flags, ", ") + "\n")
+		}
+
+		prefix := ""
+		if entry.Obsolete {
+			prefix = "#~ "
+		}
+		if entry.PreviousID != "" {
+			writeString(out, "#| ", "msgid", entry.PreviousID)
+		}
+		if entry.Context != "" {
+			writeString(out, prefix, "msgctxt", entry.Context)
+		}
+		writeString(out, prefix, "msgid", entry.ID)
+		writeString(out, prefix, "msgstr", entry.Str)
+	}
+
+	return out.Flush()
+}
+
+// Writes a keyword and its string, split after every line break the way gettext tools do
+func writeString(out *bufio.Writer, prefix, keyword, value string) {
+	lines := strings.SplitAfter(value, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if len(lines) <= 1 {
+		out.WriteString(prefix + keyword + " " + quotePO(value) + "\n")
+		return
+	}
+
+	out.WriteString(prefix + keyword + " \"\"\n")
+	for _, line := range lines {
+		out.WriteString(prefix + quotePO(line) + "\n")
+	}
+}
+
+var poEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+
+func quotePO(s string) string {
+	return `"` + poEscaper.Replace(s) + `"`
+}
@@ -0,0 +1,365 @@
+package exchange
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	parser "github.com/Diamon0/rns-babel/Parser"
+)
+
+// Where and why a PO file could not be read
+type POSyntaxError struct {
+	Line int
+	Err  error
+}
+
+func (e *POSyntaxError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *POSyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// Reads a PO or POT file. Plural forms aren't used by the game, only the first msgstr of those is kept.
+func ReadPO(r io.Reader) (*POFile, error) {
+	po := &POFile{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var entry POEntry
+	// What continuation lines add to
+	var target *string
+	// Whether the entry has anything yet, and whether it got to its msgstr
+	started, translated := false, false
+	lineNumber := 0
+
+	finish := func() {
+		if started {
+			if entry.Context == "" && entry.ID == "" && !entry.Obsolete && po.Header == "" && len(po.Entries) == 0 {
+				po.Header = entry.Str
+			} else {
+				po.Entries = append(po.Entries, entry)
+			}
+		}
+
+		entry = POEntry{}
+		target = nil
+		started, translated = false, false
+	}
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		fail := func(err error) (*POFile, error) {
+			return nil, &POSyntaxError{lineNumber, err}
+		}
+
+		if line == "" {
+			finish()
+			continue
+		}
+
+		// Comments after a finished entry start the next one
+		if strings.HasPrefix(line, "#") && translated && !strings.HasPrefix(line, "#~") {
+			finish()
+		}
+
+		previous := false
+		switch {
+		case strings.HasPrefix(line, "#~|"):
+			line, previous = strings.TrimSpace(line[3:]), true
+		case strings.HasPrefix(line, "#~"):
+			if translated && !entry.Obsolete {
+				finish()
+			}
+			line = strings.TrimSpace(line[2:])
+			entry.Obsolete, started = true, true
+		case strings.HasPrefix(line, "#|"):
+			line, previous = strings.TrimSpace(line[2:]), true
+		case strings.HasPrefix(line, "#."):
+			entry.ExtractedComments = append(entry.ExtractedComments, strings.TrimSpace(line[2:]))
+			started = true
+			continue
+		case strings.HasPrefix(line, "#:"):
+			entry.References = append(entry.References, strings.Fields(line[2:])...)
+			started = true
+			continue
+		case strings.HasPrefix(line, "#,"):
+			for _, flag := range strings.Split(line[2:], ",") {
+				if flag = strings.TrimSpace(flag); flag != "" {
+					entry.Flags = append(entry.Flags, flag)
+				}
+			}
+			started = true
+			continue
+		case strings.HasPrefix(line, "#"):
+			entry.TranslatorComments = append(entry.TranslatorComments, strings.TrimPrefix(line[1:], " "))
+			started = true
+			continue
+		}
+
+		if strings.HasPrefix(line, `"`) {
+			if target == nil {
+				return fail(errors.New("string without a keyword before it"))
+			}
+			value, err := unquotePO(line)
+			if err != nil {
+				return fail(err)
+			}
+			*target += value
+			continue
+		}
+
+		keyword, quoted, ok := strings.Cut(line, " ")
+		if !ok {
+			return fail(fmt.Errorf("expected a keyword and a string, got %q", line))
+		}
+		value, err := unquotePO(strings.TrimSpace(quoted))
+		if err != nil {
+			return fail(err)
+		}
+
+		if previous {
+			// Only the previous msgid is kept, the previous msgctxt is the same as ours
+			if keyword == "msgid" {
+				entry.PreviousID = value
+				target = &entry.PreviousID
+			} else {
+				target = new(string)
+			}
+			started = true
+			continue
+		}
+
+		// Another entry without a blank line in between
+		if translated && (keyword == "msgctxt" || keyword == "msgid") {
+			obsolete := entry.Obsolete
+			finish()
+			entry.Obsolete = obsolete
+		}
+		started = true
+
+		switch {
+		case keyword == "msgctxt":
+			entry.Context = value
+			target = &entry.Context
+		case keyword == "msgid":
+			entry.ID = value
+			target = &entry.ID
+		case keyword == "msgid_plural":
+			target = new(string)
+		case keyword == "msgstr" || keyword == "msgstr[0]":
+			entry.Str = value
+			target = &entry.Str
+			translated = true
+		case strings.HasPrefix(keyword, "msgstr["):
+			target = new(string)
+			translated = true
+		default:
+			return fail(fmt.Errorf("unknown keyword %v", keyword))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	finish()
+
+	return po, nil
+}
+
+func unquotePO(s string) (string, error) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", fmt.Errorf("expected a quoted string, got %v", s)
+	}
+	s = s[1 : len(s)-1]
+
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' {
+			return "", errors.New("unescaped quote inside a string")
+		}
+		if s[i] != '\\' {
+			out.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		if i == len(s) {
+			return "", errors.New("string ends with a backslash")
+		}
+		switch s[i] {
+		case 'n':
+			out.WriteByte('\n')
+		case 't':
+			out.WriteByte('\t')
+		case 'r':
+			out.WriteByte('\r')
+		case 'a':
+			out.WriteByte('\a')
+		case 'b':
+			out.WriteByte('\b')
+		case 'f':
+			out.WriteByte('\f')
+		case 'v':
+			out.WriteByte('\v')
+		case '\\', '"', '\'', '?':
+			out.WriteByte(s[i])
+		default:
+			return "", fmt.Errorf("unknown escape \\%c", s[i])
+		}
+	}
+
+	return out.String(), nil
+}
+
+// How a PO file is imported
+type POImportOptions struct {
+	// Language the msgstrs go into.
+	// If empty, it is worked out from the file's header (the one we write, or its Language field).
+	Language string
+
+	// Language the msgids are compared to, to find entries whose source changed.
+	// If empty, it is taken from the header, or the first language of LanguageEnable.csv is used.
+	Reference string
+
+	// Also import entries marked as fuzzy
+	IncludeFuzzy bool
+
+	// Also import entries whose source text changed since the file was exported.
+	// Dialogue lines never are, their key is their row and may belong to another line now.
+	IncludeChanged bool
+
+	// Only report what would change, don't change or save anything
+	DryRun bool
+}
+
+// What importing a PO file did
+type POImportReport struct {
+	Language string
+
+	// Contexts of the entries whose translation changed
+	Updated []string
+
+	// Entries that already had the same translation
+	Unchanged int
+
+	// Entries with an empty msgstr, which are left alone
+	Untranslated int
+
+	// Entries whose key doesn't exist anymore
+	Orphans []POEntry
+
+	// Entries marked as fuzzy, only imported with IncludeFuzzy
+	Fuzzy []POEntry
+
+	// Entries whose msgid isn't the current source text anymore, only imported with IncludeChanged (dialogue lines never are)
+	SourceChanged []POEntry
+
+	// Sheets that were saved
+	Saved []string
+}
+
+// Writes the msgstrs of a PO file into the sheets and saves the ones that changed through their Update.
+// Entries are matched by msgctxt, obsolete ones are ignored.
+func ImportPO(lfs *parser.LanguageFiles, po *POFile, opts POImportOptions) (*POImportReport, error) {
+	lang := opts.Language
+	if lang == "" {
+		lang = po.HeaderField(poLanguageField)
+	}
+	if lang == "" {
+		if code := po.HeaderField("Language"); code != "" {
+			lang, _ = languageByCode(lfs, code)
+		}
+	}
+	if err := checkLanguage(lfs, lang); err != nil {
+		return nil, fmt.Errorf("Can't tell which language the PO file is for: %w", err)
+	}
+
+	reference := opts.Reference
+	if reference == "" {
+		reference = po.HeaderField(poReferenceField)
+	}
+	reference, err := referenceLanguage(lfs, reference)
+	if err != nil {
+		return nil, err
+	}
+	if reference == lang {
+		return nil, fmt.Errorf("Refusing to import into the reference language %v", lang)
+	}
+
+	idx := parser.NewIndex(lfs)
+	report := &POImportReport{Language: lang}
+
+	for _, entry := range po.Entries {
+		if entry.Obsolete {
+			continue
+		}
+
+		sheet, key, ok := SplitContext(entry.Context)
+		target := idx.Entry(sheet, key)
+		if !ok || target == nil {
+			report.Orphans = append(report.Orphans, entry)
+			continue
+		}
+
+		if entry.Str == "" {
+			report.Untranslated++
+			continue
+		}
+
+		if entry.Fuzzy() {
+			report.Fuzzy = append(report.Fuzzy, entry)
+			if !opts.IncludeFuzzy {
+				continue
+			}
+		}
+
+		if source, _ := target.Get(reference); source != entry.ID {
+			report.SourceChanged = append(report.SourceChanged, entry)
+			if !opts.IncludeChanged || target.Dialogue != nil {
+				continue
+			}
+		}
+
+		if current, _ := target.Get(lang); current == entry.Str {
+			report.Unchanged++
+			continue
+		}
+
+		report.Updated = append(report.Updated, entry.Context)
+		if opts.DryRun {
+			continue
+		}
+		if err = idx.Set(sheet, key, lang, entry.Str); err != nil {
+			return report, err
+		}
+	}
+
+	report.Saved = idx.Dirty()
+	if err = idx.Save(); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// Reads a PO file and imports it
+func ImportPOFile(path string, lfs *parser.LanguageFiles, opts POImportOptions) (*POImportReport, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	po, err := ReadPO(file)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+
+	return ImportPO(lfs, po, opts)
+}
@@ -0,0 +1,173 @@
+package exchange
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadPO(t *testing.T) {
+	t.Log("Testing ReadPO...")
+
+	_, gameFiles := loadTestGame(t)
+	po, err := BuildPO(gameFiles, "Japanese", POOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	po.Entries[0].Flags = []string{"fuzzy", "c-format"}
+	po.Entries[0].TranslatorComments = []string{"Checked by Aiko", ""}
+	po.Entries[1].PreviousID = "Big Sword"
+
+	var out bytes.Buffer
+	if err = po.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := ReadPO(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, po) {
+		t.Errorf("Expected %+v, got %+v", po, read)
+	}
+	if !read.Entries[0].Fuzzy() || read.HeaderField("language") != "ja" {
+		t.Error("Lost the fuzzy flag or the header")
+	}
+	t.Log("Written PO files read back the same...")
+
+	_, err = ReadPO(strings.NewReader("msgid \"\"\nmsgstr \"\"\n\nmsgid \"a\"\nmsgstr \"b\n"))
+	var syntaxErr *POSyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Line != 5 {
+		t.Errorf("Expected a syntax error on line 5, got %v", err)
+	}
+
+	t.Log("ReadPO Passed!")
+}
+
+const testImportPO = `msgid ""
+msgstr ""
+"Language: ja_JP\n"
+
+msgctxt "Data/Names_Item.csv|Item_b"
+msgid "Big \"Sword\""
+msgstr "大きな剣"
+
+#, fuzzy
+msgctxt "Data/Strings_Menu.csv|Menu_start"
+msgid "Start\\nGame"
+msgstr "ゲーム開始"
+
+msgctxt "Data/Strings_Menu.csv|Menu_gold"
+msgid "{0} gold left"
+msgstr "残り{0}ゴールド"
+
+msgctxt "Dialog/frog.csv|2"
+msgid "Ribbit!"
+msgstr "ケロケロ!"
+
+msgctxt "Data/Names_Item.csv|Item_gone"
+msgid "Gone"
+msgstr "消えた"
+
+msgctxt "Dialog/frog.csv|3"
+msgid ""
+"Bye!\r\n"
+"See you."
+msgstr ""
+"じゃあね!\r\n"
+"またね。"
+
+msgctxt "Data/Names_Item.csv|Item_a"
+msgid "Apple"
+msgstr "りんご"
+
+msgctxt "Data/Names_Item.csv|Item_c"
+msgid "Nothing"
+msgstr ""
+
+#~ msgctxt "Data/Names_Item.csv|Item_a"
+#~ msgid "Apple"
+#~ msgstr "リンゴ"
+`
+
+func TestImportPO(t *testing.T) {
+	t.Log("Testing ImportPO...")
+
+	gamePath, gameFiles := loadTestGame(t)
+	po, err := ReadPO(strings.NewReader(testImportPO))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := ImportPO(&gameFiles, po, POImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Updated) != 2 || len(report.Saved) != 0 {
+		t.Errorf("Dry run should only report, got %+v", report)
+	}
+
+	report, err = ImportPO(&gameFiles, po, POImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Language != "Japanese" {
+		t.Errorf("Expected ja_JP to be Japanese, got %v", report.Language)
+	}
+	if !reflect.DeepEqual(report.Updated, []string{"Data/Names_Item.csv|Item_b", "Dialog/frog.csv|3"}) {
+		t.Errorf("Unexpected updates %v", report.Updated)
+	}
+	if report.Unchanged != 1 || report.Untranslated != 1 {
+		t.Errorf("Expected 1 unchanged and 1 untranslated entry, got %d and %d", report.Unchanged, report.Untranslated)
+	}
+	if len(report.Orphans) != 1 || report.Orphans[0].Context != "Data/Names_Item.csv|Item_gone" {
+		t.Errorf("Expected Item_gone to be an orphan, got %+v", report.Orphans)
+	}
+	if len(report.Fuzzy) != 1 || len(report.SourceChanged) != 2 || report.SourceChanged[0].Context != "Data/Strings_Menu.csv|Menu_gold" || report.SourceChanged[1].Context != "Dialog/frog.csv|2" {
+		t.Errorf("Expected Menu_start to be fuzzy and Menu_gold and the first frog line to have changed, got %+v and %+v", report.Fuzzy, report.SourceChanged)
+	}
+	if !reflect.DeepEqual(report.Saved, []string{"Data/Names_Item.csv", "Dialog/frog.csv"}) {
+		t.Errorf("Unexpected saved sheets %v", report.Saved)
+	}
+	t.Log("Report is right...")
+
+	names, err := os.ReadFile(gamePath + "/Data/Names_Item.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(names), "Item_b,2,\"Big \"\"Sword\"\"\",大きな剣,Großes Schwert\r\n") {
+		t.Errorf("Names_Item wasn't updated:\n%s", names)
+	}
+	menu, err := os.ReadFile(gamePath + "/Data/Strings_Menu.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	original, err := os.ReadFile("testdata/game/Data/Strings_Menu.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(menu, original) {
+		t.Errorf("Strings_Menu shouldn't have changed:\n%s", menu)
+	}
+	frog, err := os.ReadFile(gamePath + "/Dialog/frog.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(frog), "\"じゃあね!\r\nまたね。\"") {
+		t.Errorf("frog wasn't updated:\n%s", frog)
+	}
+
+	report, err = ImportPO(&gameFiles, po, POImportOptions{IncludeChanged: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.Updated, []string{"Data/Strings_Menu.csv|Menu_gold"}) {
+		t.Errorf("Expected only Menu_gold to be imported with IncludeChanged, dialogue lines never are, got %v", report.Updated)
+	}
+
+	t.Log("ImportPO Passed!")
+}
@@ -0,0 +1,83 @@
+package exchange
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildPO(t *testing.T) {
+	t.Log("Testing PO export...")
+
+	_, gameFiles := loadTestGame(t)
+
+	po, err := BuildPO(gameFiles, "Japanese", POOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err = po.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	expected, err := os.ReadFile("testdata/ja.po")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != string(expected) {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, out.String())
+	}
+	t.Log("PO matches...")
+
+	if _, err = BuildPO(gameFiles, "Klingon", POOptions{}); err == nil {
+		t.Error("Built a PO for a language that doesn't exist")
+	}
+
+	t.Log("PO export Passed!")
+}
+
+func TestExportPOFiles(t *testing.T) {
+	t.Log("Testing ExportPOFiles...")
+
+	_, gameFiles := loadTestGame(t)
+	dir := t.TempDir()
+
+	if err := ExportPOFiles(dir, gameFiles, POOptions{Reference: "German"}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = filepath.Base(file)
+	}
+	if strings.Join(names, " ") != "en.po ja.po template.pot" {
+		t.Errorf("Expected en.po, ja.po and template.pot, got %v", names)
+	}
+
+	pot, err := os.ReadFile(filepath.Join(dir, "template.pot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Item_b has German text, Item_c has none, and the template has no translations
+	if !strings.Contains(string(pot), "msgid \"Großes Schwert\"\nmsgstr \"\"\n") || strings.Contains(string(pot), "Item_c") || strings.Contains(string(pot), "Language:") {
+		t.Errorf("Unexpected template:\n%s", pot)
+	}
+
+	// A display name is no language code
+	delete(LanguageCodes, "Japanese")
+	t.Cleanup(func() { LanguageCodes["Japanese"] = "ja" })
+	if err = ExportPOFiles(t.TempDir(), gameFiles, POOptions{Reference: "German"}); err == nil {
+		t.Error("Exported a language without a code")
+	}
+	if code, err := LanguageCode("pt-BR"); err != nil || code != "pt-BR" {
+		t.Errorf("Expected a code to stay as it is, got %q (%v)", code, err)
+	}
+
+	t.Log("ExportPOFiles Passed!")
+}
@@ -0,0 +1,201 @@
+package exchange
+
+import (
+	"errors"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// A local translation memory, built up from TMX files of this and other games, that can be searched for earlier translations.
+// It is kept on disk as a TMX file itself.
+type TranslationMemory struct {
+	Units []TMXUnit
+
+	// Units already in, so importing the same memory twice adds nothing
+	seen map[string]bool
+}
+
+func NewTranslationMemory() *TranslationMemory {
+	return &TranslationMemory{Units: make([]TMXUnit, 0), seen: make(map[string]bool)}
+}
+
+// Loads a translation memory saved with Save. If the file doesn't exist yet, the memory starts empty.
+func LoadTranslationMemory(path string) (*TranslationMemory, error) {
+	tm := NewTranslationMemory()
+
+	document, err := ReadTMXFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return tm, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	tm.Add(document)
+
+	return tm, nil
+}
+
+// Writes the memory to a file as TMX. The old file is only replaced once the new one is fully written,
+// so a failed save doesn't cost the memory built up so far.
+func (tm *TranslationMemory) Save(path string) error {
+	document := newTMX()
+	document.Units = tm.Units
+
+	return writeFileAtomic(path, document.Write)
+}
+
+// Adds the units of a TMX document that aren't in the memory yet, and returns how many there were.
+// Units with text in less than two languages are skipped, they can't be matched to anything.
+func (tm *TranslationMemory) Add(document *TMX) int {
+	if tm.seen == nil {
+		tm.seen = make(map[string]bool)
+		for i := range tm.Units {
+			tm.seen[tmUnitKey(&tm.Units[i])] = true
+		}
+	}
+	added := 0
+
+	for _, unit := range document.Units {
+		texts := 0
+		for _, variant := range unit.Variants {
+			if variant.Text != "" {
+				texts++
+			}
+		}
+		if texts < 2 {
+			continue
+		}
+
+		key := tmUnitKey(&unit)
+		if tm.seen[key] {
+			continue
+		}
+		tm.seen[key] = true
+
+		tm.Units = append(tm.Units, unit)
+		added++
+	}
+
+	return added
+}
+
+func tmUnitKey(unit *TMXUnit) string {
+	parts := []string{unit.Property(tmxSheetProperty), unit.Property(tmxKeyProperty)}
+	for _, variant := range unit.Variants {
+		parts = append(parts, strings.ToLower(variant.Lang), string(variant.Text))
+	}
+
+	return strings.Join(parts, "\x00")
+}
+
+// Reads a TMX file into the memory, and returns how many units were new
+func ImportTMXFile(path string, tm *TranslationMemory) (int, error) {
+	document, err := ReadTMXFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return tm.Add(document), nil
+}
+
+// A translation found in the memory
+type TMMatch struct {
+	// Text in the language searched from, and its translation
+	Source string
+	Target string
+
+	// How close Source is to the text searched for, 1 being the same
+	Score float64
+
+	// Where the unit comes from, if it says
+	Sheet string
+	Key   string
+}
+
+// Translations from one language to another of texts like this one, best first.
+// Languages can be given as codes (like ja or ja-JP) or as names in LanguageCodes.
+// Matches scoring less than minScore are left out, and the same translation of the same text is only given once.
+func (tm *TranslationMemory) Search(from, text, to string, minScore float64) ([]TMMatch, error) {
+	from, err := LanguageCode(from)
+	if err != nil {
+		return nil, err
+	}
+	to, err = LanguageCode(to)
+	if err != nil {
+		return nil, err
+	}
+	matches := make([]TMMatch, 0)
+	found := make(map[[2]string]bool)
+
+	for i := range tm.Units {
+		unit := &tm.Units[i]
+		source, ok := unit.Text(from)
+		if !ok || source == "" {
+			continue
+		}
+		target, ok := unit.Text(to)
+		if !ok || target == "" || found[[2]string{source, target}] {
+			continue
+		}
+
+		score := similarity(text, source, minScore)
+		if score < minScore {
+			continue
+		}
+
+		found[[2]string{source, target}] = true
+		matches = append(matches, TMMatch{source, target, score, unit.Property(tmxSheetProperty), unit.Property(tmxKeyProperty)})
+	}
+
+	sort.SliceStable(matches, func(a, b int) bool {
+		if matches[a].Score != matches[b].Score {
+			return matches[a].Score > matches[b].Score
+		}
+		return matches[a].Source < matches[b].Source
+	})
+
+	return matches, nil
+}
+
+// How alike two texts are, from 0 to 1, by their edit distance in runes.
+// When it can't reach minScore, it gives up early and returns 0.
+func similarity(a, b string, minScore float64) float64 {
+	if a == b {
+		return 1
+	}
+
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	// The distance is at least the difference in length
+	if 1-float64(abs(len(ra)-len(rb)))/float64(longest) < minScore {
+		return 0
+	}
+
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return 1 - float64(previous[len(rb)])/float64(longest)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
@@ -0,0 +1,247 @@
+package exchange
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	parser "github.com/Diamon0/rns-babel/Parser"
+)
+
+// TMX 1.4 translation memories. Exports have a <tu> per row with a <tuv> for every language it has text in,
+// which aligns every language pair at once, and the sheet and key as x-sheet and x-key properties.
+
+// Properties that say where a unit comes from
+const (
+	tmxSheetProperty string = "x-sheet"
+	tmxKeyProperty   string = "x-key"
+)
+
+const xmlNamespace string = "http://www.w3.org/XML/1998/namespace"
+
+type TMX struct {
+	XMLName xml.Name  `xml:"tmx"`
+	Version string    `xml:"version,attr"`
+	Header  TMXHeader `xml:"header"`
+	Units   []TMXUnit `xml:"body>tu"`
+}
+
+type TMXHeader struct {
+	CreationTool        string `xml:"creationtool,attr"`
+	CreationToolVersion string `xml:"creationtoolversion,attr"`
+	SegType             string `xml:"segtype,attr"`
+	OTMF                string `xml:"o-tmf,attr"`
+	AdminLang           string `xml:"adminlang,attr"`
+
+	// Language the units are translated from, or *all* when any of them can be
+	SrcLang  string `xml:"srclang,attr"`
+	DataType string `xml:"datatype,attr"`
+}
+
+type TMXUnit struct {
+	ID         string       `xml:"tuid,attr,omitempty"`
+	Properties []TMXProp    `xml:"prop"`
+	Variants   []TMXVariant `xml:"tuv"`
+}
+
+type TMXProp struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type TMXVariant struct {
+	Lang string     `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Text tmxSegment `xml:"seg"`
+}
+
+// Takes the language from lang too, which TMX used before 1.4 instead of xml:lang
+func (v *TMXVariant) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var content struct {
+		Text tmxSegment `xml:"seg"`
+	}
+	if err := d.DecodeElement(&content, &start); err != nil {
+		return err
+	}
+
+	*v = TMXVariant{Text: content.Text}
+	for _, attr := range start.Attr {
+		if attr.Name.Local != "lang" {
+			continue
+		}
+		if attr.Name.Space == xmlNamespace || (attr.Name.Space == "" && v.Lang == "") {
+			v.Lang = attr.Value
+		}
+	}
+
+	return nil
+}
+
+// Text of a <seg>. Tools may mark up parts of it with inline elements (like <ph> or <bpt>),
+// whose content is the original text they stand for, so only the text of everything inside is kept.
+type tmxSegment string
+
+func (s *tmxSegment) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var text strings.Builder
+	for depth := 1; depth > 0; {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			text.Write(t)
+		}
+	}
+	*s = tmxSegment(text.String())
+
+	return nil
+}
+
+// A property of the unit, or empty if it doesn't have it
+func (u *TMXUnit) Property(name string) string {
+	for _, prop := range u.Properties {
+		if prop.Type == name {
+			return prop.Value
+		}
+	}
+
+	return ""
+}
+
+// The text of the unit in a language, matched as languageMatches does
+func (u *TMXUnit) Text(lang string) (string, bool) {
+	for _, variant := range u.Variants {
+		if languageMatches(variant.Lang, lang) {
+			return string(variant.Text), true
+		}
+	}
+
+	return "", false
+}
+
+// Whether two language tags (like ja, ja-JP or ja_JP) are the same language.
+// A tag without a region matches any region of its language.
+func languageMatches(a, b string) bool {
+	a = strings.ToLower(strings.ReplaceAll(a, "_", "-"))
+	b = strings.ToLower(strings.ReplaceAll(b, "_", "-"))
+	if a == b {
+		return true
+	}
+
+	primaryA, regionA, _ := strings.Cut(a, "-")
+	primaryB, regionB, _ := strings.Cut(b, "-")
+	return primaryA == primaryB && (regionA == "" || regionB == "")
+}
+
+func newTMX() *TMX {
+	return &TMX{
+		Version: "1.4",
+		Header: TMXHeader{
+			CreationTool:        "RNS-Babel",
+			CreationToolVersion: "1",
+			SegType:             "block",
+			OTMF:                "RNS-Babel",
+			AdminLang:           "en",
+			SrcLang:             "*all*",
+			DataType:            "plaintext",
+		},
+	}
+}
+
+// The translation memory of the game: a unit for every row with text in at least two languages.
+// Every language needs a code, see LanguageCode.
+func BuildTMX(lfs parser.LanguageFiles) (*TMX, error) {
+	document := newTMX()
+
+	codes := make(map[string]string)
+	for _, name := range lfs.Languages.Names() {
+		code, err := LanguageCode(name)
+		if err != nil {
+			return nil, err
+		}
+		codes[name] = code
+	}
+
+	for _, entry := range entries(&lfs) {
+		unit := TMXUnit{
+			ID: Context(entry.Sheet, entry.Key),
+			Properties: []TMXProp{
+				{tmxSheetProperty, entry.Sheet},
+				{tmxKeyProperty, entry.Key},
+			},
+		}
+
+		for _, translation := range *entry.Translations {
+			if code, ok := codes[translation.Language]; ok && translation.String != "" {
+				unit.Variants = append(unit.Variants, TMXVariant{Lang: code, Text: tmxSegment(translation.String)})
+			}
+		}
+		if len(unit.Variants) < 2 {
+			continue
+		}
+
+		document.Units = append(document.Units, unit)
+	}
+
+	return document, nil
+}
+
+// Writes the document, with the XML declaration
+func (t *TMX) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(t); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// Reads a TMX document
+func ReadTMX(r io.Reader) (*TMX, error) {
+	var document TMX
+	if err := xml.NewDecoder(r).Decode(&document); err != nil {
+		return nil, err
+	}
+
+	return &document, nil
+}
+
+// Writes the translation memory of the game to a file
+func ExportTMXFile(path string, lfs parser.LanguageFiles) error {
+	document, err := BuildTMX(lfs)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(filepath.Dir(path), filepath.Base(path), document.Write)
+}
+
+// Reads a TMX file
+func ReadTMXFile(path string) (*TMX, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	document, err := ReadTMX(file)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+
+	return document, nil
+}
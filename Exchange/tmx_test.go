@@ -0,0 +1,200 @@
+package exchange
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildTMX(t *testing.T) {
+	t.Log("Testing TMX export...")
+
+	_, gameFiles := loadTestGame(t)
+
+	document, err := BuildTMX(gameFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if document.Version != "1.4" || document.Header.SrcLang != "*all*" {
+		t.Errorf("Unexpected header %+v", document.Header)
+	}
+
+	ids := make([]string, len(document.Units))
+	for i, unit := range document.Units {
+		ids[i] = unit.ID
+	}
+	// Item_c has no text and the second frog line only has English
+	expected := []string{"Data/Names_Item.csv|Item_a", "Data/Names_Item.csv|Item_b", "Data/Strings_Menu.csv|Menu_start", "Data/Strings_Menu.csv|Menu_gold", "Dialog/frog.csv|2"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Fatalf("Expected units %v, got %v", expected, ids)
+	}
+
+	item := document.Units[1]
+	if item.Property("x-sheet") != "Data/Names_Item.csv" || item.Property("x-key") != "Item_b" {
+		t.Errorf("Expected the sheet and key as properties, got %+v", item.Properties)
+	}
+	if len(item.Variants) != 2 || item.Variants[0].Lang != "en" || item.Variants[1].Lang != "de" {
+		t.Errorf("Expected Item_b in English and German only, got %+v", item.Variants)
+	}
+	if text, ok := document.Units[0].Text("ja-JP"); !ok || text != "りんご" {
+		t.Errorf("Expected ja-JP to find the Japanese text, got %q", text)
+	}
+	t.Log("Units are right...")
+
+	var out bytes.Buffer
+	if err = document.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `<tuv xml:lang="en">`) || !strings.Contains(out.String(), `<prop type="x-key">Item_a</prop>`) {
+		t.Errorf("Unexpected TMX:\n%s", out.String())
+	}
+
+	read, err := ReadTMX(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read.Units, document.Units) || read.Header != document.Header {
+		t.Errorf("Expected %+v, got %+v", document, read)
+	}
+	t.Log("Written TMX reads back the same...")
+
+	// Inline markup keeps its text, and TMX 1.1 used lang without xml:
+	old := `<tmx version="1.1"><header srclang="EN-US"/><body><tu>` +
+		`<tuv lang="EN-US"><seg>Hit <bpt i="1">&lt;b&gt;</bpt>{0}<ept i="1">&lt;/b&gt;</ept> times</seg></tuv>` +
+		`<tuv lang="JA-JP"><seg><ph>{0}</ph>回</seg></tuv></tu></body></tmx>`
+	read, err = ReadTMX(strings.NewReader(old))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := read.Units[0].Text("en"); text != "Hit <b>{0}</b> times" {
+		t.Errorf("Expected the inline markup's text to be kept, got %q", text)
+	}
+	if text, _ := read.Units[0].Text("Japanese"); text != "" {
+		t.Errorf("Units match language codes, not names, got %q", text)
+	}
+
+	if _, err = ReadTMX(strings.NewReader(`<xliff version="2.0"></xliff>`)); err == nil {
+		t.Error("Read something that isn't TMX")
+	}
+
+	t.Log("TMX export Passed!")
+}
+
+func TestTranslationMemory(t *testing.T) {
+	t.Log("Testing TranslationMemory...")
+
+	_, gameFiles := loadTestGame(t)
+	dir := t.TempDir()
+	exported := filepath.Join(dir, "game.tmx")
+	store := filepath.Join(dir, "memory.tmx")
+
+	if err := ExportTMXFile(exported, gameFiles); err != nil {
+		t.Fatal(err)
+	}
+
+	tm, err := LoadTranslationMemory(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tm.Units) != 0 {
+		t.Fatalf("A memory that was never saved should be empty, got %d units", len(tm.Units))
+	}
+
+	added, err := ImportTMXFile(exported, tm)
+	if err != nil || added != 5 {
+		t.Fatalf("Expected 5 units to be added, got %d (%v)", added, err)
+	}
+	added, err = ImportTMXFile(exported, tm)
+	if err != nil || added != 0 {
+		t.Errorf("Importing the same memory again shouldn't add anything, got %d (%v)", added, err)
+	}
+
+	other := `<tmx version="1.4"><header srclang="en"/><body>` +
+		`<tu><tuv xml:lang="en-US"><seg>Apples</seg></tuv><tuv xml:lang="ja-JP"><seg>りんごたち</seg></tuv></tu>` +
+		`<tu><tuv xml:lang="en"><seg>Apple</seg></tuv><tuv xml:lang="ja"><seg>りんご</seg></tuv></tu>` +
+		`<tu><tuv xml:lang="en"><seg>Lonely</seg></tuv></tu></body></tmx>`
+	document, err := ReadTMX(strings.NewReader(other))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added = tm.Add(document); added != 2 {
+		t.Errorf("Expected the two aligned units to be added, got %d", added)
+	}
+	t.Log("Imports are right...")
+
+	if err = tm.Save(store); err != nil {
+		t.Fatal(err)
+	}
+
+	// A save that fails halfway leaves the saved memory alone
+	failed := errors.New("disk full")
+	err = writeFileAtomic(store, func(w io.Writer) error {
+		io.WriteString(w, "<tmx")
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Errorf("Expected the write to fail, got %v", err)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 2 {
+		t.Errorf("Expected only the two memories to be left, got %v", files)
+	}
+
+	tm, err = LoadTranslationMemory(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tm.Units) != 7 {
+		t.Fatalf("Expected 7 units after loading the saved memory, got %d", len(tm.Units))
+	}
+
+	matches, err := tm.Search("English", "Apple", "ja", 0.7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []TMMatch{
+		{"Apple", "りんご", 1, "Data/Names_Item.csv", "Item_a"},
+		{"Apples", "りんごたち", 1 - 1.0/6, "", ""},
+	}
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, matches)
+	}
+	if matches, _ = tm.Search("de", "Quak!", "en", 0.8); len(matches) != 1 || matches[0].Target != "Ribbit." || matches[0].Key != "2" {
+		t.Errorf("Expected Quak. to be found from German, got %+v", matches)
+	}
+	if matches, _ = tm.Search("en", "Something else entirely", "ja", 0.5); len(matches) != 0 {
+		t.Errorf("Expected nothing to match, got %+v", matches)
+	}
+	if _, err = tm.Search("Klingon", "Apple", "ja", 0.5); err == nil {
+		t.Error("Searched from a language without a code")
+	}
+
+	t.Log("TranslationMemory Passed!")
+}
+
+func TestSimilarity(t *testing.T) {
+	t.Log("Testing similarity...")
+
+	for _, c := range []struct {
+		a, b  string
+		score float64
+	}{
+		{"", "", 1},
+		{"kitten", "sitting", 1 - 3.0/7},
+		{"りんご", "りんごたち", 1 - 2.0/5},
+		{"abc", "", 0},
+	} {
+		if score := similarity(c.a, c.b, 0); score != c.score {
+			t.Errorf("Expected %v for %q and %q, got %v", c.score, c.a, c.b, score)
+		}
+	}
+	if score := similarity("a", "abcdefgh", 0.5); score != 0 {
+		t.Errorf("Expected to give up when the lengths are too far apart, got %v", score)
+	}
+
+	t.Log("similarity Passed!")
+}
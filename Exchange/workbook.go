@@ -0,0 +1,324 @@
+package exchange
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	parser "github.com/Diamon0/rns-babel/Parser"
+)
+
+// A workbook with every sheet and dialogue file as a worksheet, with every language they have,
+// for people who'd rather have everything in one spreadsheet.
+// A hidden worksheet keeps where every row comes from, so edits go back to the right row of the right file,
+// and what every language cell held when it was exported, so only the cells edited in the workbook are written back.
+
+// Name of the hidden worksheet
+const workbookMetadataSheet string = "_rns"
+
+// Exported is followed by the exported value of every language column of the worksheet, in the same order
+var workbookMetadataHeader []string = []string{"Worksheet", "Row", "Source", "Source row", "Key", "Exported"}
+
+// The worksheets of the workbook, the hidden one last
+func BuildWorkbook(lfs parser.LanguageFiles) ([]Worksheet, error) {
+	sheets := make([]Worksheet, 0)
+	indexes := make(map[string]int)
+	names := make(map[string]bool)
+	metadata := Worksheet{
+		Name:       workbookMetadataSheet,
+		Rows:       [][]string{workbookMetadataHeader},
+		FrozenRows: 1,
+		Hidden:     true,
+	}
+	names[strings.ToLower(metadata.Name)] = true
+
+	for _, entry := range entries(&lfs) {
+		index, ok := indexes[entry.Sheet]
+		if !ok {
+			index = len(sheets)
+			indexes[entry.Sheet] = index
+
+			header := workbookFields(&entry)
+			widths := make([]float64, 0, len(header)+len(entry.Languages))
+			for _, field := range header {
+				if field == "Key" {
+					widths = append(widths, 24)
+				} else {
+					widths = append(widths, 12)
+				}
+			}
+			for _, lang := range entry.Languages {
+				header = append(header, lang)
+				widths = append(widths, 40)
+			}
+
+			sheets = append(sheets, Worksheet{
+				Name:       worksheetName(entry.Sheet, names),
+				Rows:       [][]string{header},
+				FrozenRows: 1,
+				Widths:     widths,
+			})
+		}
+
+		row := make([]string, 0, len(sheets[index].Rows[0]))
+		switch {
+		case entry.KeyLevel != nil:
+			row = append(row, entry.Key, strconv.Itoa(entry.KeyLevel.Level))
+		case entry.Dialogue != nil:
+			row = append(row, entry.DialogueFields()...)
+		default:
+			row = append(row, entry.Key)
+		}
+		fields := len(row)
+		for _, lang := range entry.Languages {
+			value, _ := entry.Get(lang)
+			row = append(row, value)
+		}
+
+		sheets[index].Rows = append(sheets[index].Rows, row)
+		metadata.Rows = append(metadata.Rows, append([]string{
+			sheets[index].Name,
+			strconv.Itoa(len(sheets[index].Rows)),
+			entry.Sheet,
+			strconv.Itoa(entry.Row + 2),
+			entry.Key,
+		}, row[fields:]...))
+	}
+
+	if len(sheets) == 0 {
+		return nil, errors.New("There is nothing to put in a workbook")
+	}
+
+	return append(sheets, metadata), nil
+}
+
+// Headers of the columns that come before the languages
+func workbookFields(entry *parser.Entry) []string {
+	switch {
+	case entry.KeyLevel != nil:
+		return []string{"Key", "Level"}
+	case entry.Dialogue != nil:
+		return []string{"Type", "Flag", "Expression"}
+	}
+
+	return []string{"Key"}
+}
+
+// A worksheet name for a sheet that isn't taken yet, like Names_Item for Data/Names_Item.csv
+func worksheetName(sheet string, names map[string]bool) string {
+	base := invalidSheetName.ReplaceAllString(strings.TrimSuffix(path.Base(sheet), path.Ext(sheet)), "_")
+	if runes := []rune(base); len(runes) > 26 {
+		base = string(runes[:26])
+	}
+
+	name := base
+	for i := 2; names[strings.ToLower(name)]; i++ {
+		name = fmt.Sprintf("%v (%d)", base, i)
+	}
+	names[strings.ToLower(name)] = true
+
+	return name
+}
+
+// Writes the workbook to a file
+func ExportWorkbook(filePath string, lfs parser.LanguageFiles) error {
+	sheets, err := BuildWorkbook(lfs)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filePath, func(w io.Writer) error {
+		return WriteXLSX(w, sheets)
+	})
+}
+
+// How a workbook is imported
+type WorkbookImportOptions struct {
+	// Only report what would change, don't change or save anything
+	DryRun bool
+}
+
+// What importing a workbook did
+type WorkbookImportReport struct {
+	// Contexts of the entries where any language changed
+	Updated []string
+
+	// How many cells changed across every language
+	Cells int
+
+	// Rows where nothing changed
+	Unchanged int
+
+	// Contexts of the rows whose sheet or key doesn't exist anymore
+	Orphans []string
+
+	// Contexts of the rows whose key in the worksheet isn't the one they were exported with
+	// (or, for dialogue lines, whose type, flag or expression isn't the one of the line they come from),
+	// which happens when rows are sorted or moved. They are left alone.
+	Moved []string
+
+	// Contexts of the dialogue lines whose text in the reference language (the first one in LanguageEnable.csv)
+	// changed since the export. Their key is their row, so it may be another line now. They are left alone.
+	Drifted []string
+
+	// Contexts of the rows with cells that were edited in the workbook,
+	// but were also changed in the game files since it was exported. Those cells are left alone.
+	Conflicts []string
+
+	// Sheets that were saved
+	Saved []string
+}
+
+// Writes every language column of the workbook back into the rows the hidden worksheet says they come from,
+// and saves the sheets that changed through their Update. Only cells edited since the export are written,
+// so a workbook that was only looked at leaves every file exactly as it was,
+// and changes made to the game files some other way in the meantime aren't undone.
+// Columns other than the languages (keys, levels and dialogue fields) are only there to read.
+func ImportWorkbook(lfs *parser.LanguageFiles, sheets []Worksheet, opts WorkbookImportOptions) (*WorkbookImportReport, error) {
+	worksheets := make(map[string]*Worksheet)
+	var metadata *Worksheet
+	for i := range sheets {
+		worksheets[sheets[i].Name] = &sheets[i]
+		if sheets[i].Name == workbookMetadataSheet {
+			metadata = &sheets[i]
+		}
+	}
+	if metadata == nil || len(metadata.Rows) == 0 || strings.Join(metadata.Rows[0], ",") != strings.Join(workbookMetadataHeader, ",") {
+		return nil, fmt.Errorf("The workbook has no %v worksheet, it wasn't exported by us", workbookMetadataSheet)
+	}
+
+	reference, err := referenceLanguage(lfs, "")
+	if err != nil {
+		return nil, err
+	}
+
+	idx := parser.NewIndex(lfs)
+	report := &WorkbookImportReport{}
+
+	for i, record := range metadata.Rows[1:] {
+		if len(record) < len(workbookMetadataHeader)-1 {
+			return nil, fmt.Errorf("Row %d of %v is incomplete", i+2, workbookMetadataSheet)
+		}
+		name, sheet, key := record[0], record[2], record[4]
+		rowNumber, err := strconv.Atoi(record[1])
+		if err != nil || rowNumber < 2 {
+			return nil, fmt.Errorf("Row %d of %v has no valid row number", i+2, workbookMetadataSheet)
+		}
+		context := Context(sheet, key)
+
+		worksheet, ok := worksheets[name]
+		if !ok || len(worksheet.Rows) == 0 {
+			return nil, fmt.Errorf("Worksheet %v is missing or empty", name)
+		}
+		target := idx.Entry(sheet, key)
+		if target == nil {
+			report.Orphans = append(report.Orphans, context)
+			continue
+		}
+
+		header := worksheet.Rows[0]
+		var row []string
+		if rowNumber <= len(worksheet.Rows) {
+			row = worksheet.Rows[rowNumber-1]
+		}
+		cell := func(column int) string {
+			if column < len(row) {
+				return row[column]
+			}
+			return ""
+		}
+
+		// Dialogue lines have no key column, their key is where they are,
+		// so the only thing to tell them apart by is their fields
+		moved := cell(0) != key
+		if target.Dialogue != nil {
+			fields := target.DialogueFields()
+			moved = cell(0) != fields[0] || cell(1) != fields[1] || cell(2) != fields[2]
+		}
+		if moved {
+			report.Moved = append(report.Moved, context)
+			continue
+		}
+
+		// Spreadsheets drop empty cells at the end of a row, those were exported empty
+		fields := len(workbookFields(target))
+		exported := func(column int) string {
+			if at := len(workbookMetadataHeader) - 1 + column - fields; at < len(record) {
+				return record[at]
+			}
+			return ""
+		}
+
+		if target.Dialogue != nil {
+			source, _ := target.Get(reference)
+			drifted := true
+			for column := fields; column < len(header); column++ {
+				if header[column] == reference {
+					drifted = source != exported(column)
+				}
+			}
+			if drifted {
+				report.Drifted = append(report.Drifted, context)
+				continue
+			}
+		}
+
+		changed, conflict := false, false
+		for column := fields; column < len(header); column++ {
+			value, was := cell(column), exported(column)
+			current, ok := target.Get(header[column])
+			if !ok || value == was || value == current {
+				continue
+			}
+			if current != was {
+				conflict = true
+				continue
+			}
+
+			changed = true
+			report.Cells++
+			if opts.DryRun {
+				continue
+			}
+			if err = idx.Set(sheet, key, header[column], value); err != nil {
+				return report, err
+			}
+		}
+
+		if conflict {
+			report.Conflicts = append(report.Conflicts, context)
+		}
+		if changed {
+			report.Updated = append(report.Updated, context)
+		} else if !conflict {
+			report.Unchanged++
+		}
+	}
+
+	report.Saved = idx.Dirty()
+	if err := idx.Save(); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// Reads a workbook and imports it
+func ImportWorkbookFile(filePath string, lfs *parser.LanguageFiles, opts WorkbookImportOptions) (*WorkbookImportReport, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	sheets, err := ReadXLSX(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", filePath, err)
+	}
+
+	return ImportWorkbook(lfs, sheets, opts)
+}
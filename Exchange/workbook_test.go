@@ -0,0 +1,179 @@
+package exchange
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	parser "github.com/Diamon0/rns-babel/Parser"
+)
+
+func TestBuildWorkbook(t *testing.T) {
+	t.Log("Testing BuildWorkbook...")
+
+	_, gameFiles := loadTestGame(t)
+
+	sheets, err := BuildWorkbook(gameFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, len(sheets))
+	for i, sheet := range sheets {
+		names[i] = sheet.Name
+	}
+	if !reflect.DeepEqual(names, []string{"Names_Item", "Strings_Menu", "frog", workbookMetadataSheet}) || !sheets[3].Hidden || sheets[0].FrozenRows != 1 {
+		t.Fatalf("Unexpected worksheets %+v", sheets)
+	}
+
+	// Every row is there, untranslated ones too, but not the spacer
+	expected := [][]string{
+		{"Key", "Level", "English", "Japanese", "German"},
+		{"Item_a", "1", "Apple", "りんご", "Apfel"},
+		{"Item_b", "2", `Big "Sword"`, "", "Großes Schwert"},
+		{"Item_c", "3", "", "", ""},
+	}
+	if !reflect.DeepEqual(sheets[0].Rows, expected) {
+		t.Errorf("Expected %v, got %v", expected, sheets[0].Rows)
+	}
+	if !reflect.DeepEqual(sheets[2].Rows[2], []string{"1", "flag_frog", "smile", "Bye!\r\nSee you.", "", ""}) {
+		t.Errorf("Unexpected dialogue row %v", sheets[2].Rows[2])
+	}
+	// Fields keep the text they have in the file
+	if !reflect.DeepEqual(sheets[2].Rows[1][:3], []string{"0", "007", "1"}) {
+		t.Errorf("Expected the flag to stay 007, got %v", sheets[2].Rows[1])
+	}
+	if !reflect.DeepEqual(sheets[3].Rows[3], []string{"Names_Item", "4", "Data/Names_Item.csv", "5", "Item_c", "", "", ""}) {
+		t.Errorf("Expected Item_c to come from row 5 with its exported values, got %v", sheets[3].Rows[3])
+	}
+
+	t.Log("BuildWorkbook Passed!")
+}
+
+func TestWorkbookRoundTrip(t *testing.T) {
+	t.Log("Testing workbook export and import...")
+
+	gamePath, gameFiles := loadTestGame(t)
+	workbook := filepath.Join(t.TempDir(), "game.xlsx")
+
+	if err := ExportWorkbook(workbook, gameFiles); err != nil {
+		t.Fatal(err)
+	}
+	report, err := ImportWorkbookFile(workbook, &gameFiles, WorkbookImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Updated) != 0 || report.Unchanged != 7 || len(report.Saved) != 0 {
+		t.Errorf("Importing an untouched workbook shouldn't change anything, got %+v", report)
+	}
+	t.Log("Untouched workbook changes nothing...")
+
+	data, err := os.ReadFile(workbook)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sheets, err := ReadXLSX(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sheets[0].Rows[2][3] = "大きな剣"
+	sheets[0].Rows[1][4] = ""
+	sheets[0].Rows[2][4] = "Riesiges Schwert"
+	sheets[2].Rows[2][3] = "Bye!\r\nSee you soon."
+	sheets[1].Rows[1], sheets[1].Rows[2] = sheets[1].Rows[2], sheets[1].Rows[1]
+	// Dialogue has no key to go by, a different type means it isn't the same line
+	sheets[2].Rows[1][0] = "3"
+	sheets[2].Rows[1][5] = "Quak?"
+	sheets[3].Rows = append(sheets[3].Rows, []string{"Names_Item", "5", "Data/Names_Item.csv", "6", "Item_gone", "", "", ""})
+
+	// Changed in the game files after the export: Item_b's German clashes with the workbook,
+	// Item_c's English was only changed in the files and has to stay
+	idx := parser.NewIndex(&gameFiles)
+	if err = idx.Set("Data/Names_Item.csv", "Item_b", "German", "Großes Schwert!"); err != nil {
+		t.Fatal(err)
+	}
+	if err = idx.Set("Data/Names_Item.csv", "Item_c", "English", "Shield"); err != nil {
+		t.Fatal(err)
+	}
+	if err = idx.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err = ImportWorkbook(&gameFiles, sheets, WorkbookImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Cells != 3 || len(report.Saved) != 0 {
+		t.Errorf("Dry run should only report, got %+v", report)
+	}
+
+	report, err = ImportWorkbook(&gameFiles, sheets, WorkbookImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.Updated, []string{"Data/Names_Item.csv|Item_a", "Data/Names_Item.csv|Item_b", "Dialog/frog.csv|3"}) || report.Cells != 3 {
+		t.Errorf("Unexpected updates %v (%d cells)", report.Updated, report.Cells)
+	}
+	if !reflect.DeepEqual(report.Moved, []string{"Data/Strings_Menu.csv|Menu_start", "Data/Strings_Menu.csv|Menu_gold", "Dialog/frog.csv|2"}) {
+		t.Errorf("Expected the swapped menu rows to be left alone, got %v", report.Moved)
+	}
+	if !reflect.DeepEqual(report.Orphans, []string{"Data/Names_Item.csv|Item_gone"}) {
+		t.Errorf("Expected Item_gone to be an orphan, got %v", report.Orphans)
+	}
+	if !reflect.DeepEqual(report.Conflicts, []string{"Data/Names_Item.csv|Item_b"}) {
+		t.Errorf("Expected Item_b's German to clash, got %v", report.Conflicts)
+	}
+	if !reflect.DeepEqual(report.Saved, []string{"Data/Names_Item.csv", "Dialog/frog.csv"}) {
+		t.Errorf("Unexpected saved sheets %v", report.Saved)
+	}
+	t.Log("Report is right...")
+
+	names, err := os.ReadFile(gamePath + "/Data/Names_Item.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Key,Level,English,Japanese,German\r\nItem_a,1,Apple,りんご,\r\n,,,,\r\nItem_b,2,\"Big \"\"Sword\"\"\",大きな剣,Großes Schwert!\r\nItem_c,3,Shield,,\r\n"
+	if string(names) != expected {
+		t.Errorf("Expected Names_Item to be:\n%q\ngot:\n%q", expected, names)
+	}
+
+	frog, err := os.ReadFile(gamePath + "/Dialog/frog.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "type,flag,expression,English,Japanese,German\r\n0,007,1,Ribbit.,ケロ。,Quak.\r\n1,flag_frog,smile,\"Bye!\r\nSee you soon.\",,\r\n"
+	if string(frog) != expected {
+		t.Errorf("Expected frog to be:\n%q\ngot:\n%q", expected, frog)
+	}
+
+	t.Log("Workbook export and import Passed!")
+}
+
+func TestWorkbookDialogueDrift(t *testing.T) {
+	t.Log("Testing workbook dialogue drift...")
+
+	_, gameFiles := loadTestGame(t)
+	sheets, err := BuildWorkbook(gameFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sheets[2].Rows[2][4] = "じゃあね!"
+
+	// A line was put in before it, so row 3 is another line now
+	idx := parser.NewIndex(&gameFiles)
+	if err = idx.Set("Dialog/frog.csv", "3", "English", "Hop hop."); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := ImportWorkbook(&gameFiles, sheets, WorkbookImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.Drifted, []string{"Dialog/frog.csv|3"}) || report.Cells != 0 {
+		t.Errorf("Expected the frog line to be left alone, got %+v", report)
+	}
+
+	t.Log("Workbook dialogue drift Passed!")
+}
@@ -0,0 +1,355 @@
+package exchange
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	parser "github.com/Diamon0/rns-babel/Parser"
+)
+
+// XLIFF 2.0 documents, one per target language.
+// Every sheet is a <file> whose original is its sheet ID,
+// and every row a <unit> named after its key, with the level or the dialogue fields as notes.
+
+const xliffNamespace string = "urn:oasis:names:tc:xliff:document:2.0"
+
+type XLIFF struct {
+	XMLName xml.Name    `xml:"urn:oasis:names:tc:xliff:document:2.0 xliff"`
+	Version string      `xml:"version,attr"`
+	SrcLang string      `xml:"srcLang,attr"`
+	TrgLang string      `xml:"trgLang,attr,omitempty"`
+	Files   []XLIFFFile `xml:"file"`
+}
+
+type XLIFFFile struct {
+	ID string `xml:"id,attr"`
+
+	// Sheet ID of the sheet
+	Original string `xml:"original,attr,omitempty"`
+
+	// Game text is taken exactly as it is
+	Space string `xml:"http://www.w3.org/XML/1998/namespace space,attr,omitempty"`
+
+	Units []XLIFFUnit `xml:"unit"`
+}
+
+type XLIFFUnit struct {
+	ID string `xml:"id,attr"`
+
+	// Key of the row
+	Name string `xml:"name,attr,omitempty"`
+
+	// Nil when there are none, XLIFF doesn't allow an empty <notes>
+	Notes    *XLIFFNotes    `xml:"notes"`
+	Segments []XLIFFSegment `xml:"segment"`
+}
+
+type XLIFFNotes struct {
+	Notes []XLIFFNote `xml:"note"`
+}
+
+type XLIFFNote struct {
+	Category string `xml:"category,attr,omitempty"`
+	Text     string `xml:",chardata"`
+}
+
+type XLIFFSegment struct {
+	State  string  `xml:"state,attr,omitempty"`
+	Source string  `xml:"source"`
+	Target *string `xml:"target"`
+}
+
+// The source text of a unit, put together from all its segments, in case a tool split it
+func (u *XLIFFUnit) Source() string {
+	var source strings.Builder
+	for _, segment := range u.Segments {
+		source.WriteString(segment.Source)
+	}
+
+	return source.String()
+}
+
+// The target text of a unit, and whether any segment has one
+func (u *XLIFFUnit) Target() (string, bool) {
+	var target strings.Builder
+	found := false
+	for _, segment := range u.Segments {
+		if segment.Target != nil {
+			target.WriteString(*segment.Target)
+			found = true
+		}
+	}
+
+	return target.String(), found
+}
+
+// How XLIFF files are exported
+type XLIFFOptions struct {
+	// Language the sources come from.
+	// If empty, the first language of LanguageEnable.csv is used.
+	Reference string
+}
+
+// The XLIFF document of a language. Entries without reference text are left out,
+// and entries without a translation get no target.
+func BuildXLIFF(lfs parser.LanguageFiles, lang string, opts XLIFFOptions) (*XLIFF, error) {
+	if err := checkLanguage(&lfs, lang); err != nil {
+		return nil, err
+	}
+	reference, err := referenceLanguage(&lfs, opts.Reference)
+	if err != nil {
+		return nil, err
+	}
+
+	srcLang, err := LanguageCode(reference)
+	if err != nil {
+		return nil, err
+	}
+	trgLang, err := LanguageCode(lang)
+	if err != nil {
+		return nil, err
+	}
+
+	document := &XLIFF{
+		Version: "2.0",
+		SrcLang: srcLang,
+		TrgLang: trgLang,
+	}
+	files := make(map[string]int)
+
+	for _, entry := range entries(&lfs) {
+		source, _ := entry.Get(reference)
+		if source == "" {
+			continue
+		}
+
+		index, ok := files[entry.Sheet]
+		if !ok {
+			index = len(document.Files)
+			files[entry.Sheet] = index
+			document.Files = append(document.Files, XLIFFFile{
+				ID:       "f" + strconv.Itoa(index+1),
+				Original: entry.Sheet,
+				Space:    "preserve",
+			})
+		}
+
+		unit := XLIFFUnit{
+			// Keys aren't always valid ids, rows always are
+			ID:   "r" + strconv.Itoa(entry.Row+2),
+			Name: entry.Key,
+		}
+		notes := make([]XLIFFNote, 0)
+		for _, note := range entryFields(&entry) {
+			notes = append(notes, XLIFFNote{note[0], note[1]})
+		}
+		if len(notes) > 0 {
+			unit.Notes = &XLIFFNotes{notes}
+		}
+
+		segment := XLIFFSegment{State: "initial", Source: source}
+		if target, _ := entry.Get(lang); target != "" {
+			segment.State = "translated"
+			segment.Target = &target
+		}
+		unit.Segments = []XLIFFSegment{segment}
+
+		document.Files[index].Units = append(document.Files[index].Units, unit)
+	}
+
+	return document, nil
+}
+
+// Writes the document, with the XML declaration
+func (x *XLIFF) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(x); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// Reads an XLIFF 2.0 document
+func ReadXLIFF(r io.Reader) (*XLIFF, error) {
+	var document XLIFF
+	if err := xml.NewDecoder(r).Decode(&document); err != nil {
+		return nil, err
+	}
+
+	if document.XMLName.Space != xliffNamespace || !strings.HasPrefix(document.Version, "2.") {
+		return nil, fmt.Errorf("Not an XLIFF 2 document (version %q)", document.Version)
+	}
+
+	return &document, nil
+}
+
+// How an XLIFF document is imported
+type XLIFFImportOptions struct {
+	// Skip units whose source drifted and import the rest, instead of refusing the whole document
+	SkipDrifted bool
+
+	// Only report what would change, don't change or save anything
+	DryRun bool
+}
+
+// A unit of an imported document, and the file it is in
+type XLIFFUnitRef struct {
+	Sheet string
+	Unit  XLIFFUnit
+}
+
+// What importing an XLIFF document did
+type XLIFFImportReport struct {
+	Language string
+
+	// Contexts of the entries whose translation changed
+	Updated []string
+
+	// Units that already had the same translation
+	Unchanged int
+
+	// Units without a target or with an empty one, which are left alone
+	Untranslated int
+
+	// Units whose sheet or key doesn't exist anymore
+	Orphans []XLIFFUnitRef
+
+	// Units whose source isn't the current source text anymore
+	Drifted []XLIFFUnitRef
+
+	// Sheets that were saved
+	Saved []string
+}
+
+// Writes the targets of an XLIFF document into the sheets and saves the ones that changed through their Update.
+// The languages are taken from srcLang and trgLang.
+//
+// Before anything is applied, every source is checked against the current source text.
+// If any of them drifted, nothing is changed and the report comes back with an error,
+// unless SkipDrifted is set.
+func ImportXLIFF(lfs *parser.LanguageFiles, document *XLIFF, opts XLIFFImportOptions) (*XLIFFImportReport, error) {
+	reference, ok := languageByCode(lfs, document.SrcLang)
+	if !ok {
+		return nil, fmt.Errorf("Source language %v is not in LanguageEnable.csv", document.SrcLang)
+	}
+	lang, ok := languageByCode(lfs, document.TrgLang)
+	if !ok {
+		return nil, fmt.Errorf("Target language %v is not in LanguageEnable.csv", document.TrgLang)
+	}
+	if reference == lang {
+		return nil, fmt.Errorf("Refusing to import into the reference language %v", lang)
+	}
+
+	idx := parser.NewIndex(lfs)
+	report := &XLIFFImportReport{Language: lang}
+
+	type change struct {
+		sheet, key, value string
+	}
+	changes := make([]change, 0)
+
+	for _, file := range document.Files {
+		for _, unit := range file.Units {
+			target := idx.Entry(file.Original, unit.Name)
+			if target == nil {
+				report.Orphans = append(report.Orphans, XLIFFUnitRef{file.Original, unit})
+				continue
+			}
+
+			if source, _ := target.Get(reference); source != unit.Source() {
+				report.Drifted = append(report.Drifted, XLIFFUnitRef{file.Original, unit})
+				continue
+			}
+
+			value, ok := unit.Target()
+			if !ok || value == "" {
+				report.Untranslated++
+				continue
+			}
+
+			if current, _ := target.Get(lang); current == value {
+				report.Unchanged++
+				continue
+			}
+
+			changes = append(changes, change{file.Original, unit.Name, value})
+			report.Updated = append(report.Updated, Context(file.Original, unit.Name))
+		}
+	}
+
+	if len(report.Drifted) > 0 && !opts.SkipDrifted {
+		return report, fmt.Errorf("The source text of %d units changed since they were exported, nothing was imported", len(report.Drifted))
+	}
+	if opts.DryRun {
+		return report, nil
+	}
+
+	for _, c := range changes {
+		if err := idx.Set(c.sheet, c.key, lang, c.value); err != nil {
+			return report, err
+		}
+	}
+
+	report.Saved = idx.Dirty()
+	if err := idx.Save(); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// Writes an XLIFF document for every language but the reference one into a folder, as <language code>.xlf
+func ExportXLIFFFiles(dir string, lfs parser.LanguageFiles, opts XLIFFOptions) error {
+	reference, err := referenceLanguage(&lfs, opts.Reference)
+	if err != nil {
+		return err
+	}
+	opts.Reference = reference
+
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for _, lang := range lfs.Languages.Names() {
+		if lang == reference {
+			continue
+		}
+
+		document, err := BuildXLIFF(lfs, lang, opts)
+		if err != nil {
+			return err
+		}
+		if err = writeFile(dir, document.TrgLang+".xlf", document.Write); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Reads an XLIFF document and imports it
+func ImportXLIFFFile(path string, lfs *parser.LanguageFiles, opts XLIFFImportOptions) (*XLIFFImportReport, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	document, err := ReadXLIFF(file)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+
+	return ImportXLIFF(lfs, document, opts)
+}
@@ -0,0 +1,227 @@
+package exchange
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildXLIFF(t *testing.T) {
+	t.Log("Testing XLIFF export...")
+
+	_, gameFiles := loadTestGame(t)
+
+	document, err := BuildXLIFF(gameFiles, "Japanese", XLIFFOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if document.SrcLang != "en" || document.TrgLang != "ja" {
+		t.Errorf("Expected en to ja, got %v to %v", document.SrcLang, document.TrgLang)
+	}
+
+	originals := make([]string, len(document.Files))
+	for i, file := range document.Files {
+		originals[i] = file.Original
+	}
+	if !reflect.DeepEqual(originals, []string{"Data/Names_Item.csv", "Data/Strings_Menu.csv", "Dialog/frog.csv"}) {
+		t.Fatalf("Unexpected files %v", originals)
+	}
+
+	items := document.Files[0].Units
+	if len(items) != 2 || items[0].Name != "Item_a" || items[1].Name != "Item_b" || items[1].ID != "r4" {
+		t.Fatalf("Expected Item_a and Item_b (row 4), got %+v", items)
+	}
+	if _, ok := items[1].Target(); ok || items[1].Segments[0].State != "initial" {
+		t.Errorf("Item_b has no Japanese text and shouldn't have a target, got %+v", items[1].Segments)
+	}
+	if target, ok := items[0].Target(); !ok || target != "りんご" || items[0].Segments[0].State != "translated" {
+		t.Errorf("Expected Item_a to be translated as りんご, got %+v", items[0].Segments)
+	}
+	if !reflect.DeepEqual(items[0].Notes, &XLIFFNotes{[]XLIFFNote{{"Level", "1"}}}) {
+		t.Errorf("Expected the level as a note, got %+v", items[0].Notes)
+	}
+
+	frog := document.Files[2].Units
+	if len(frog) != 2 || frog[1].Name != "3" || frog[1].Source() != "Bye!\r\nSee you." {
+		t.Fatalf("Unexpected dialogue units %+v", frog)
+	}
+	if !reflect.DeepEqual(frog[1].Notes, &XLIFFNotes{[]XLIFFNote{{"Type", "1"}, {"Flag", "flag_frog"}, {"Expression", "smile"}}}) {
+		t.Errorf("Expected the dialogue fields as notes, got %+v", frog[1].Notes)
+	}
+	t.Log("Units are right...")
+
+	var out bytes.Buffer
+	if err = document.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `<file id="f1" original="Data/Names_Item.csv" xml:space="preserve">`) || strings.Contains(out.String(), "<notes></notes>") {
+		t.Errorf("Unexpected file element:\n%s", out.String())
+	}
+
+	read, err := ReadXLIFF(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read.Files, document.Files) {
+		t.Errorf("Expected %+v, got %+v", document.Files, read.Files)
+	}
+	t.Log("Written documents read back the same...")
+
+	if _, err = ReadXLIFF(strings.NewReader(`<xliff xmlns="urn:oasis:names:tc:xliff:document:1.2" version="1.2"></xliff>`)); err == nil {
+		t.Error("Read an XLIFF 1.2 document")
+	}
+
+	t.Log("XLIFF export Passed!")
+}
+
+// Translates Item_b and the second frog line, like a CAT tool would
+func translateXLIFF(t *testing.T, document *XLIFF) {
+	t.Helper()
+
+	for i := range document.Files {
+		for j := range document.Files[i].Units {
+			unit := &document.Files[i].Units[j]
+			switch unit.Name {
+			case "Item_b":
+				target := "大きな剣"
+				unit.Segments[0].Target = &target
+			case "3":
+				// Tools may split a unit into segments
+				first, second := "じゃあね!\r\n", "またね。"
+				unit.Segments = []XLIFFSegment{{Source: "Bye!\r\n", Target: &first}, {Source: "See you.", Target: &second}}
+			}
+		}
+	}
+}
+
+func TestImportXLIFF(t *testing.T) {
+	t.Log("Testing ImportXLIFF...")
+
+	gamePath, gameFiles := loadTestGame(t)
+	document, err := BuildXLIFF(gameFiles, "Japanese", XLIFFOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	translateXLIFF(t, document)
+	document.Files[0].Units = append(document.Files[0].Units, XLIFFUnit{ID: "r9", Name: "Item_gone", Segments: []XLIFFSegment{{Source: "Gone"}}})
+
+	report, err := ImportXLIFF(&gameFiles, document, XLIFFImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Updated) != 2 || len(report.Saved) != 0 {
+		t.Errorf("Dry run should only report, got %+v", report)
+	}
+
+	report, err = ImportXLIFF(&gameFiles, document, XLIFFImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Language != "Japanese" {
+		t.Errorf("Expected ja to be Japanese, got %v", report.Language)
+	}
+	if !reflect.DeepEqual(report.Updated, []string{"Data/Names_Item.csv|Item_b", "Dialog/frog.csv|3"}) {
+		t.Errorf("Unexpected updates %v", report.Updated)
+	}
+	// Item_a, Menu_start, Menu_gold and the first frog line already have their Japanese text
+	if report.Unchanged != 4 || report.Untranslated != 0 {
+		t.Errorf("Expected 4 unchanged and no untranslated units, got %d and %d", report.Unchanged, report.Untranslated)
+	}
+	if len(report.Orphans) != 1 || report.Orphans[0].Unit.Name != "Item_gone" {
+		t.Errorf("Expected Item_gone to be an orphan, got %+v", report.Orphans)
+	}
+	if !reflect.DeepEqual(report.Saved, []string{"Data/Names_Item.csv", "Dialog/frog.csv"}) {
+		t.Errorf("Unexpected saved sheets %v", report.Saved)
+	}
+	t.Log("Report is right...")
+
+	frog, err := os.ReadFile(gamePath + "/Dialog/frog.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(frog), "\"じゃあね!\r\nまたね。\"") {
+		t.Errorf("frog wasn't updated:\n%s", frog)
+	}
+
+	t.Log("ImportXLIFF Passed!")
+}
+
+func TestImportXLIFFDrifted(t *testing.T) {
+	t.Log("Testing ImportXLIFF with drifted sources...")
+
+	gamePath, gameFiles := loadTestGame(t)
+	document, err := BuildXLIFF(gameFiles, "Japanese", XLIFFOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	translateXLIFF(t, document)
+	document.Files[0].Units[1].Segments[0].Source = "Big Sword"
+
+	report, err := ImportXLIFF(&gameFiles, document, XLIFFImportOptions{})
+	if err == nil {
+		t.Fatal("Imported a document whose source drifted")
+	}
+	if len(report.Drifted) != 1 || report.Drifted[0].Sheet != "Data/Names_Item.csv" || report.Drifted[0].Unit.Name != "Item_b" {
+		t.Errorf("Expected Item_b to have drifted, got %+v", report.Drifted)
+	}
+
+	original, err := os.ReadFile("testdata/game/Dialog/frog.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	frog, err := os.ReadFile(gamePath + "/Dialog/frog.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(frog, original) {
+		t.Errorf("Nothing should be imported when a source drifted, got:\n%s", frog)
+	}
+	t.Log("Refused the whole document...")
+
+	report, err = ImportXLIFF(&gameFiles, document, XLIFFImportOptions{SkipDrifted: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.Updated, []string{"Dialog/frog.csv|3"}) || !reflect.DeepEqual(report.Saved, []string{"Dialog/frog.csv"}) {
+		t.Errorf("Expected only the frog line to be imported, got %+v", report)
+	}
+
+	t.Log("ImportXLIFF with drifted sources Passed!")
+}
+
+func TestExportXLIFFFiles(t *testing.T) {
+	t.Log("Testing ExportXLIFFFiles...")
+
+	_, gameFiles := loadTestGame(t)
+	dir := t.TempDir()
+
+	if err := ExportXLIFFFiles(dir, gameFiles, XLIFFOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = filepath.Base(file)
+	}
+	if strings.Join(names, " ") != "de.xlf ja.xlf" {
+		t.Errorf("Expected de.xlf and ja.xlf, got %v", names)
+	}
+
+	_, gameFiles = loadTestGame(t)
+	report, err := ImportXLIFFFile(filepath.Join(dir, "de.xlf"), &gameFiles, XLIFFImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Language != "German" || len(report.Updated) != 0 || len(report.Saved) != 0 {
+		t.Errorf("Importing an untouched export shouldn't change anything, got %+v", report)
+	}
+
+	t.Log("ExportXLIFFFiles Passed!")
+}
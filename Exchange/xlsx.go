@@ -0,0 +1,427 @@
+package exchange
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Just enough of Office Open XML spreadsheets to write and read back plain text tables,
+// without pulling in a spreadsheet library. Every cell is written as a text cell, so nothing gets turned into a number or a date.
+
+// A table of a workbook
+type Worksheet struct {
+	// At most 31 characters, none of []:*?/\
+	Name string
+
+	// Rows of cells, the first one being the header
+	Rows [][]string
+
+	// Rows kept at the top while scrolling, which are also bold
+	FrozenRows int
+
+	// Width of each column, in characters. Columns past the end get the default one.
+	Widths []float64
+
+	Hidden bool
+}
+
+const (
+	xlsxMainNamespace      string = "http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+	xlsxRelationsNamespace string = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	xlsxPackageRelations   string = "http://schemas.openxmlformats.org/package/2006/relationships"
+)
+
+// The most a worksheet can have, anything past them is a broken or crafted workbook
+const (
+	xlsxMaxRows    int = 1048576
+	xlsxMaxColumns int = 16384
+)
+
+// Styles: 0 is the default, 1 is the bold header, 2 is wrapped text
+const xlsxStyles string = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="` + xlsxMainNamespace + `">
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="3"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="49" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1" applyNumberFormat="1"/><xf numFmtId="49" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1" applyAlignment="1"><alignment vertical="top" wrapText="1"/></xf></cellXfs>
+<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>
+</styleSheet>
+`
+
+var invalidSheetName = regexp.MustCompile(`[\[\]:*?/\\]`)
+
+// Writes the worksheets as an XLSX workbook, in order. The first one that isn't hidden is the one shown when it's opened.
+func WriteXLSX(w io.Writer, sheets []Worksheet) error {
+	names := make(map[string]bool)
+	visible := false
+	for _, sheet := range sheets {
+		name := strings.ToLower(sheet.Name)
+		if sheet.Name == "" || len([]rune(sheet.Name)) > 31 || invalidSheetName.MatchString(sheet.Name) {
+			return fmt.Errorf("%q can't be the name of a worksheet", sheet.Name)
+		}
+		if names[name] {
+			return fmt.Errorf("There are two worksheets called %v", sheet.Name)
+		}
+		names[name] = true
+		visible = visible || !sheet.Hidden
+	}
+	if !visible {
+		return errors.New("A workbook needs at least one worksheet that isn't hidden")
+	}
+
+	archive := zip.NewWriter(w)
+	add := func(name, content string) error {
+		file, err := archive.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(file, content)
+		return err
+	}
+
+	var contentTypes, workbook, relations strings.Builder
+	contentTypes.WriteString(xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	workbook.WriteString(xml.Header + `<workbook xmlns="` + xlsxMainNamespace + `" xmlns:r="` + xlsxRelationsNamespace + `"><sheets>`)
+	relations.WriteString(xml.Header + `<Relationships xmlns="` + xlsxPackageRelations + `">` +
+		`<Relationship Id="rIdStyles" Type="` + xlsxRelationsNamespace + `/styles" Target="styles.xml"/>`)
+
+	for i, sheet := range sheets {
+		id := strconv.Itoa(i + 1)
+		part := "worksheets/sheet" + id + ".xml"
+
+		contentTypes.WriteString(`<Override PartName="/xl/` + part + `" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`)
+		workbook.WriteString(`<sheet name="` + escapeXML(sheet.Name) + `" sheetId="` + id + `" r:id="rId` + id + `"`)
+		if sheet.Hidden {
+			workbook.WriteString(` state="hidden"`)
+		}
+		workbook.WriteString(`/>`)
+		relations.WriteString(`<Relationship Id="rId` + id + `" Type="` + xlsxRelationsNamespace + `/worksheet" Target="` + part + `"/>`)
+
+		if err := add("xl/"+part, worksheetXML(&sheet)); err != nil {
+			return err
+		}
+	}
+
+	contentTypes.WriteString(`</Types>`)
+	workbook.WriteString(`</sheets></workbook>`)
+	relations.WriteString(`</Relationships>`)
+
+	parts := [][2]string{
+		{"[Content_Types].xml", contentTypes.String()},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="` + xlsxPackageRelations + `">` +
+			`<Relationship Id="rId1" Type="` + xlsxRelationsNamespace + `/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+		{"xl/workbook.xml", workbook.String()},
+		{"xl/_rels/workbook.xml.rels", relations.String()},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, part := range parts {
+		if err := add(part[0], part[1]); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+func worksheetXML(sheet *Worksheet) string {
+	var out strings.Builder
+	out.WriteString(xml.Header + `<worksheet xmlns="` + xlsxMainNamespace + `">`)
+
+	if sheet.FrozenRows > 0 {
+		out.WriteString(fmt.Sprintf(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="%d" topLeftCell="A%d" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`, sheet.FrozenRows, sheet.FrozenRows+1))
+	}
+	if len(sheet.Widths) > 0 {
+		out.WriteString(`<cols>`)
+		for i, width := range sheet.Widths {
+			out.WriteString(fmt.Sprintf(`<col min="%d" max="%d" width="%g" customWidth="1"/>`, i+1, i+1, width))
+		}
+		out.WriteString(`</cols>`)
+	}
+
+	out.WriteString(`<sheetData>`)
+	for i, row := range sheet.Rows {
+		out.WriteString(`<row r="` + strconv.Itoa(i+1) + `">`)
+
+		style := "2"
+		if i < sheet.FrozenRows {
+			style = "1"
+		}
+		for j, value := range row {
+			if value == "" {
+				continue
+			}
+			out.WriteString(`<c r="` + cellName(j, i) + `" s="` + style + `" t="inlineStr"><is><t xml:space="preserve">`)
+			out.WriteString(escapeXML(encodeXString(value)))
+			out.WriteString(`</t></is></c>`)
+		}
+
+		out.WriteString(`</row>`)
+	}
+	out.WriteString(`</sheetData></worksheet>`)
+
+	return out.String()
+}
+
+func escapeXML(s string) string {
+	var out strings.Builder
+	xml.EscapeText(&out, []byte(s))
+	return out.String()
+}
+
+// Control characters can't be in XML, spreadsheets write them as _xHHHH_ (and a _x that would be read as one as _x005F_x)
+var xStringEscape = regexp.MustCompile(`_x[0-9A-Fa-f]{4}_|[\x00-\x08\x0B-\x1F]`)
+
+func encodeXString(s string) string {
+	return xStringEscape.ReplaceAllStringFunc(s, func(match string) string {
+		if len(match) == 1 {
+			return fmt.Sprintf("_x%04X_", match[0])
+		}
+		return "_x005F_" + match[1:]
+	})
+}
+
+var xStringUnescape = regexp.MustCompile(`_x[0-9A-Fa-f]{4}_`)
+
+func decodeXString(s string) string {
+	return xStringUnescape.ReplaceAllStringFunc(s, func(match string) string {
+		r, _ := strconv.ParseUint(match[2:6], 16, 32)
+		return string(rune(r))
+	})
+}
+
+// Name of a cell, like B3, from its column and row starting at 0
+func cellName(column, row int) string {
+	name := ""
+	for column++; column > 0; column = (column - 1) / 26 {
+		name = string(rune('A'+(column-1)%26)) + name
+	}
+
+	return name + strconv.Itoa(row+1)
+}
+
+// Column and row of a cell name, starting at 0
+func parseCellName(name string) (int, int, error) {
+	column := 0
+	i := 0
+	for ; i < len(name) && name[i] >= 'A' && name[i] <= 'Z' && column <= xlsxMaxColumns; i++ {
+		column = column*26 + int(name[i]-'A'+1)
+	}
+
+	row, err := strconv.Atoi(name[i:])
+	if i == 0 || err != nil || row < 1 {
+		return 0, 0, fmt.Errorf("%q is not a cell", name)
+	}
+	if column > xlsxMaxColumns || row > xlsxMaxRows {
+		return 0, 0, fmt.Errorf("Cell %q is past the end of a worksheet", name)
+	}
+
+	return column - 1, row - 1, nil
+}
+
+type xlsxRelations struct {
+	Relations []struct {
+		ID     string `xml:"Id,attr"`
+		Type   string `xml:"Type,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name  string `xml:"name,attr"`
+		ID    string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		State string `xml:"state,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+// Text of a shared or inline string, which may be split into runs
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t *xlsxText) String() string {
+	text := t.Text
+	for _, run := range t.Runs {
+		text += run.Text
+	}
+
+	return decodeXString(text)
+}
+
+type xlsxWorksheet struct {
+	Panes []struct {
+		YSplit float64 `xml:"ySplit,attr"`
+		State  string  `xml:"state,attr"`
+	} `xml:"sheetViews>sheetView>pane"`
+	Columns []struct {
+		Min   int     `xml:"min,attr"`
+		Max   int     `xml:"max,attr"`
+		Width float64 `xml:"width,attr"`
+	} `xml:"cols>col"`
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R      string    `xml:"r,attr"`
+			T      string    `xml:"t,attr"`
+			V      string    `xml:"v"`
+			Inline *xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// Reads every worksheet of an XLSX workbook as text, in order, hidden ones included.
+// Numbers and booleans come back the way they are stored, formulas as their last value.
+func ReadXLSX(r io.ReaderAt, size int64) ([]Worksheet, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make(map[string]*zip.File)
+	for _, file := range archive.File {
+		parts[strings.TrimPrefix(file.Name, "/")] = file
+	}
+	decode := func(name string, v any) error {
+		file, ok := parts[name]
+		if !ok {
+			return fmt.Errorf("The workbook has no %v", name)
+		}
+		reader, err := file.Open()
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+
+		if err = xml.NewDecoder(reader).Decode(v); err != nil {
+			return fmt.Errorf("%v: %w", name, err)
+		}
+		return nil
+	}
+
+	var workbook xlsxWorkbook
+	if err = decode("xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	var relations xlsxRelations
+	if err = decode("xl/_rels/workbook.xml.rels", &relations); err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]string)
+	sharedStrings := make([]string, 0)
+	for _, relation := range relations.Relations {
+		target := relation.Target
+		if strings.HasPrefix(target, "/") {
+			target = target[1:]
+		} else {
+			target = path.Join("xl", target)
+		}
+		targets[relation.ID] = target
+
+		if strings.HasSuffix(relation.Type, "/sharedStrings") {
+			var shared struct {
+				Items []xlsxText `xml:"si"`
+			}
+			if err = decode(target, &shared); err != nil {
+				return nil, err
+			}
+			for i := range shared.Items {
+				sharedStrings = append(sharedStrings, shared.Items[i].String())
+			}
+		}
+	}
+
+	sheets := make([]Worksheet, 0, len(workbook.Sheets))
+	for _, entry := range workbook.Sheets {
+		var data xlsxWorksheet
+		if err = decode(targets[entry.ID], &data); err != nil {
+			return nil, err
+		}
+
+		sheet := Worksheet{Name: entry.Name, Hidden: entry.State == "hidden" || entry.State == "veryHidden"}
+		for _, pane := range data.Panes {
+			if pane.State == "frozen" || pane.State == "frozenSplit" {
+				sheet.FrozenRows = int(pane.YSplit)
+			}
+		}
+		for _, column := range data.Columns {
+			for i := column.Min; i <= column.Max && i <= 1024; i++ {
+				for len(sheet.Widths) < i {
+					sheet.Widths = append(sheet.Widths, 0)
+				}
+				sheet.Widths[i-1] = column.Width
+			}
+		}
+
+		for i, row := range data.Rows {
+			// Rows and cells may leave out where they are, then they come right after the previous one
+			rowIndex := len(sheet.Rows)
+			if row.R > 0 {
+				rowIndex = row.R - 1
+			}
+			if rowIndex < len(sheet.Rows) {
+				return nil, fmt.Errorf("%v: row %d is out of order", entry.Name, i+1)
+			}
+			if rowIndex >= xlsxMaxRows {
+				return nil, fmt.Errorf("%v: row %d is past the end of a worksheet", entry.Name, i+1)
+			}
+			for len(sheet.Rows) <= rowIndex {
+				sheet.Rows = append(sheet.Rows, nil)
+			}
+
+			var cells []string
+			for _, cell := range row.Cells {
+				column := len(cells)
+				if cell.R != "" {
+					if column, _, err = parseCellName(cell.R); err != nil {
+						return nil, fmt.Errorf("%v: %w", entry.Name, err)
+					}
+				}
+				if column < len(cells) {
+					return nil, fmt.Errorf("%v: cell %v is out of order", entry.Name, cell.R)
+				}
+				if column >= xlsxMaxColumns {
+					return nil, fmt.Errorf("%v: row %d has more cells than a worksheet can have", entry.Name, i+1)
+				}
+				for len(cells) <= column {
+					cells = append(cells, "")
+				}
+
+				switch cell.T {
+				case "inlineStr":
+					if cell.Inline != nil {
+						cells[column] = cell.Inline.String()
+					}
+				case "s":
+					index, err := strconv.Atoi(cell.V)
+					if err != nil || index < 0 || index >= len(sharedStrings) {
+						return nil, fmt.Errorf("%v: cell %v points to a shared string that doesn't exist", entry.Name, cell.R)
+					}
+					cells[column] = sharedStrings[index]
+				default:
+					cells[column] = decodeXString(cell.V)
+				}
+			}
+			sheet.Rows[rowIndex] = cells
+		}
+
+		sheets = append(sheets, sheet)
+	}
+
+	return sheets, nil
+}
@@ -0,0 +1,157 @@
+package exchange
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCellName(t *testing.T) {
+	t.Log("Testing cell names...")
+
+	for name, position := range map[string][2]int{"A1": {0, 0}, "Z3": {25, 2}, "AA10": {26, 9}, "AZ1": {51, 0}, "BA2": {52, 1}} {
+		if got := cellName(position[0], position[1]); got != name {
+			t.Errorf("Expected %v for %v, got %v", name, position, got)
+		}
+		column, row, err := parseCellName(name)
+		if err != nil || column != position[0] || row != position[1] {
+			t.Errorf("Expected %v for %v, got %d, %d (%v)", position, name, column, row, err)
+		}
+	}
+	for _, name := range []string{"", "12", "A", "A0", "a1", "XFE1", "A1048577", "AAAAAAAAAAAAAAAAAAAA1"} {
+		if _, _, err := parseCellName(name); err == nil {
+			t.Errorf("Took %q as a cell", name)
+		}
+	}
+
+	t.Log("Cell names Passed!")
+}
+
+func TestXLSX(t *testing.T) {
+	t.Log("Testing XLSX round trip...")
+
+	sheets := []Worksheet{
+		{
+			Name:       "Names",
+			Rows:       [][]string{{"Key", "English"}, {"007", "Big \"Sword\" & <b>shield</b>"}, nil, {"", "Bye!\r\nSee you.\tTab", "_x0041_ stays"}},
+			FrozenRows: 1,
+			Widths:     []float64{24, 40},
+		},
+		{
+			Name:   "_meta",
+			Rows:   [][]string{{"ユニコード"}},
+			Hidden: true,
+		},
+	}
+
+	var out bytes.Buffer
+	if err := WriteXLSX(&out, sheets); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadXLSX(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Empty cells at the end of a row aren't written
+	sheets[0].Rows[3] = []string{"", "Bye!\r\nSee you.\tTab", "_x0041_ stays"}
+	if !reflect.DeepEqual(read, sheets) {
+		t.Errorf("Expected %+v, got %+v", sheets, read)
+	}
+	t.Log("Worksheets read back the same...")
+
+	if err = WriteXLSX(&out, []Worksheet{{Name: "a/b"}}); err == nil {
+		t.Error("Wrote a worksheet with a slash in its name")
+	}
+	if err = WriteXLSX(&out, []Worksheet{{Name: "a"}, {Name: "A"}}); err == nil {
+		t.Error("Wrote two worksheets with the same name")
+	}
+	if err = WriteXLSX(&out, []Worksheet{{Name: "a", Hidden: true}}); err == nil {
+		t.Error("Wrote a workbook with only hidden worksheets")
+	}
+
+	t.Log("XLSX round trip Passed!")
+}
+
+// A workbook the way spreadsheet programs save them: shared strings, rich text, numbers and cells without positions
+func TestReadXLSXSharedStrings(t *testing.T) {
+	t.Log("Testing ReadXLSX with shared strings...")
+
+	parts := map[string]string{
+		"xl/workbook.xml": `<?xml version="1.0"?><workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId3"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<?xml version="1.0"?><Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="/xl/worksheets/first.xml"/>` +
+			`<Relationship Id="rId4" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/sharedStrings" Target="sharedStrings.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<?xml version="1.0"?><sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<si><t>Key</t></si><si><r><t>Big </t></r><r><rPr><b/></rPr><t>Sword</t></r><rPh><t>ignored</t></rPh></si><si><t>Line_x000D_` + "\n" + `Break</t></si></sst>`,
+		"xl/worksheets/first.xml": `<?xml version="1.0"?><worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+			`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c></row>` +
+			`<row r="3"><c><v>42</v></c><c t="b"><v>1</v></c><c t="s"><v>2</v></c></row></sheetData></worksheet>`,
+	}
+
+	out := zipParts(t, parts)
+	read, err := ReadXLSX(bytes.NewReader(out), int64(len(out)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Worksheet{{Name: "Sheet1", Rows: [][]string{{"Key", "", "Big Sword"}, nil, {"42", "1", "Line\r\nBreak"}}}}
+	if !reflect.DeepEqual(read, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, read)
+	}
+
+	if _, err = ReadXLSX(strings.NewReader("not a zip"), 9); err == nil {
+		t.Error("Read something that isn't a workbook")
+	}
+
+	t.Log("ReadXLSX with shared strings Passed!")
+}
+
+// Zips the parts of a workbook written by hand
+func zipParts(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+
+	var out bytes.Buffer
+	archive := zip.NewWriter(&out)
+	for name, content := range parts {
+		file, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = file.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return out.Bytes()
+}
+
+func TestReadXLSXLimits(t *testing.T) {
+	t.Log("Testing ReadXLSX with rows and cells past the end...")
+
+	for _, rows := range []string{
+		`<row r="999999999"><c><v>1</v></c></row>`,
+		`<row r="1"><c r="A999999999"><v>1</v></c></row>`,
+		`<row r="1"><c r="XFE1"><v>1</v></c></row>`,
+	} {
+		out := zipParts(t, map[string]string{
+			"xl/workbook.xml": `<?xml version="1.0"?><workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+				`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+			"xl/_rels/workbook.xml.rels": `<?xml version="1.0"?><Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+				`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`,
+			"xl/worksheets/sheet1.xml": `<?xml version="1.0"?><worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+				rows + `</sheetData></worksheet>`,
+		})
+
+		if _, err := ReadXLSX(bytes.NewReader(out), int64(len(out))); err == nil {
+			t.Errorf("Read a worksheet with %v", rows)
+		}
+	}
+
+	t.Log("ReadXLSX with rows and cells past the end Passed!")
+}
@@ -0,0 +1,288 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Every game file is copied here before it is changed.
+// Each snapshot gets its own timestamped folder with a manifest of what it holds,
+// and a file is only copied the first time it changes within a snapshot,
+// so a snapshot always holds the files as they were when it started.
+// A relative folder is taken as relative to the game folder (the one holding Data and Dialog),
+// not to wherever the tool was started from.
+var BackupDir string = "Backups"
+
+const backupManifestName string = "manifest.json"
+const snapshotIDFormat string = "2006-01-02_15-04-05.000000"
+
+// A backed up file
+type BackupEntry struct {
+	// Absolute path of the original file
+	Path string `json:"path"`
+
+	// Name of the copy inside the snapshot folder
+	Copy string `json:"copy"`
+
+	// Checksum of the copy, verified before restoring it
+	SHA256 string `json:"sha256"`
+
+	Size int64 `json:"size"`
+
+	// Whether the file didn't exist yet, restoring it means removing it
+	Missing bool `json:"missing,omitempty"`
+}
+
+// A folder of backed up files, named after the time it was taken
+type Snapshot struct {
+	ID      string        `json:"id"`
+	Created time.Time     `json:"created"`
+	Files   []BackupEntry `json:"files"`
+
+	// Folder the snapshot folder is in
+	root string
+}
+
+// The snapshot files are currently being backed up to
+var currentSnapshot *Snapshot
+var backupMutex sync.Mutex
+
+// Ends the current snapshot, files changed after this are backed up to a new one.
+// The folder itself is only created once a file is actually backed up.
+func StartSnapshot() {
+	backupMutex.Lock()
+	currentSnapshot = nil
+	backupMutex.Unlock()
+}
+
+// Copies a file into the current snapshot, unless it is already there
+func backupFile(path string) error {
+	backupMutex.Lock()
+	defer backupMutex.Unlock()
+
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	// Game files are in Data or Dialog, right under the game folder
+	root, err := backupRoot(filepath.Dir(filepath.Dir(path)))
+	if err != nil {
+		return err
+	}
+
+	if currentSnapshot == nil || currentSnapshot.root != root {
+		snapshot, err := createSnapshot(root)
+		if err != nil {
+			return err
+		}
+		currentSnapshot = snapshot
+	}
+
+	for _, entry := range currentSnapshot.Files {
+		if entry.Path == path {
+			return nil
+		}
+	}
+
+	entry := BackupEntry{
+		Path: path,
+		Copy: fmt.Sprintf("%03d_%s", len(currentSnapshot.Files), filepath.Base(path)),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		entry.Missing = true
+	} else if err != nil {
+		return err
+	} else {
+		if err = WriteFileAtomic(filepath.Join(root, currentSnapshot.ID, entry.Copy), data, 0644); err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(data)
+		entry.SHA256 = hex.EncodeToString(sum[:])
+		entry.Size = int64(len(data))
+	}
+
+	currentSnapshot.Files = append(currentSnapshot.Files, entry)
+
+	if err = saveManifest(currentSnapshot); err != nil {
+		currentSnapshot.Files = currentSnapshot.Files[:len(currentSnapshot.Files)-1]
+		return err
+	}
+
+	return nil
+}
+
+// Folder the snapshots of a game are kept in
+func backupRoot(gamePath string) (string, error) {
+	if filepath.IsAbs(BackupDir) {
+		return BackupDir, nil
+	}
+
+	return filepath.Abs(filepath.Join(gamePath, BackupDir))
+}
+
+// Creates the folder for a new snapshot in root
+func createSnapshot(root string) (*Snapshot, error) {
+	created := time.Now()
+	id := created.Format(snapshotIDFormat)
+
+	// Don't ever reuse a folder, however unlikely
+	for i := 1; ; i++ {
+		if _, err := os.Stat(filepath.Join(root, id)); errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		id = fmt.Sprintf("%s_%d", created.Format(snapshotIDFormat), i)
+	}
+
+	if err := os.MkdirAll(filepath.Join(root, id), 0755); err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{
+		ID:      id,
+		Created: created,
+		root:    root,
+	}
+
+	return snapshot, saveManifest(snapshot)
+}
+
+func saveManifest(snapshot *Snapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return WriteFileAtomic(filepath.Join(snapshot.root, snapshot.ID, backupManifestName), data, 0644)
+}
+
+// Lists every snapshot of the game in gamePath, oldest first
+func ListSnapshots(gamePath string) ([]Snapshot, error) {
+	root, err := backupRoot(gamePath)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs, err := os.ReadDir(root)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]Snapshot, 0, len(dirs))
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(root, dir.Name(), backupManifestName))
+		if errors.Is(err, fs.ErrNotExist) {
+			// Not one of ours
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		var snapshot Snapshot
+		if err = json.Unmarshal(data, &snapshot); err != nil {
+			return nil, fmt.Errorf("Backup manifest of %v is corrupted: %w", dir.Name(), err)
+		}
+		snapshot.root = root
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		if snapshots[i].Created.Equal(snapshots[j].Created) {
+			return snapshots[i].ID < snapshots[j].ID
+		}
+		return snapshots[i].Created.Before(snapshots[j].Created)
+	})
+
+	return snapshots, nil
+}
+
+// Rolls every backed up file of the game in gamePath back to how it was when the snapshot was taken.
+//
+// A snapshot only holds the files that changed while it was current,
+// so newer snapshots are taken into account too:
+// every file changed since then is restored from the oldest copy made after that point.
+// Every copy is checked against its checksum before anything is touched,
+// and the current state is itself backed up first, so a restore can be undone.
+func RestoreSnapshot(gamePath, id string) error {
+	snapshots, err := ListSnapshots(gamePath)
+	if err != nil {
+		return err
+	}
+
+	start := -1
+	for i, snapshot := range snapshots {
+		if snapshot.ID == id {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return fmt.Errorf("Backup snapshot %v does not exist", id)
+	}
+
+	type restoration struct {
+		entry BackupEntry
+		data  []byte
+	}
+
+	// Walk from the newest one back, so older copies win
+	restorations := make(map[string]restoration)
+	order := make([]string, 0)
+	for i := len(snapshots) - 1; i >= start; i-- {
+		for _, entry := range snapshots[i].Files {
+			if _, ok := restorations[entry.Path]; !ok {
+				order = append(order, entry.Path)
+			}
+
+			if entry.Missing {
+				restorations[entry.Path] = restoration{entry: entry}
+				continue
+			}
+
+			data, err := os.ReadFile(filepath.Join(snapshots[i].root, snapshots[i].ID, entry.Copy))
+			if err != nil {
+				return err
+			}
+
+			sum := sha256.Sum256(data)
+			if hex.EncodeToString(sum[:]) != entry.SHA256 {
+				return fmt.Errorf("Backup of %v in snapshot %v does not match its checksum", entry.Path, snapshots[i].ID)
+			}
+
+			restorations[entry.Path] = restoration{entry: entry, data: data}
+		}
+	}
+
+	// Whatever is there right now goes into a snapshot of its own
+	StartSnapshot()
+	defer StartSnapshot()
+
+	sort.Strings(order)
+	writes := make([]pendingWrite, 0, len(order))
+	for _, path := range order {
+		r := restorations[path]
+		writes = append(writes, pendingWrite{path: path, data: r.data, create: true, remove: r.entry.Missing})
+	}
+
+	// Either every file is restored or none are
+	return writeGameFiles(writes)
+}
@@ -0,0 +1,222 @@
+package parser
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	t.Log("Testing backups...")
+
+	file := openFixture(t, "Names_Test.csv")
+	gamePath := filepath.Dir(filepath.Dir(file.Name()))
+	original, err := os.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sheet := &NameSheet{File: file}
+	if err = sheet.Parse(); err != nil {
+		t.Fatalf("Failed to parse with error:\n%v", err)
+	}
+
+	sheet.Strings[0].Strings[0].String = "Sword?"
+	if err = sheet.Update(); err != nil {
+		t.Fatalf("Failed to update with error:\n%v", err)
+	}
+
+	sheet.Strings[0].Strings[0].String = "Sword!"
+	if err = sheet.Update(); err != nil {
+		t.Fatalf("Failed to update with error:\n%v", err)
+	}
+	edited, err := os.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshots, err := ListSnapshots(gamePath)
+	if err != nil {
+		t.Fatalf("Failed to list snapshots with error:\n%v", err)
+	}
+	if len(snapshots) != 1 || len(snapshots[0].Files) != 1 {
+		t.Fatalf("Expected a single snapshot with a single file, got %+v", snapshots)
+	}
+	t.Log("Snapshot taken...")
+
+	backup, err := os.ReadFile(filepath.Join(BackupDir, snapshots[0].ID, snapshots[0].Files[0].Copy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(backup, original) {
+		t.Errorf("Backup does not hold the original file")
+	}
+
+	// Second snapshot, holding the edits from the first one
+	StartSnapshot()
+	sheet.Strings[0].Strings[0].String = "Sword..."
+	if err = sheet.Update(); err != nil {
+		t.Fatalf("Failed to update with error:\n%v", err)
+	}
+
+	snapshots, err = ListSnapshots(gamePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("Expected two snapshots, got %+v", snapshots)
+	}
+
+	if err = RestoreSnapshot(gamePath, snapshots[0].ID); err != nil {
+		t.Fatalf("Failed to restore snapshot with error:\n%v", err)
+	}
+	restored, err := os.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(restored, original) {
+		t.Errorf("Restoring the first snapshot did not bring back the original file")
+	}
+	t.Log("First snapshot restored...")
+
+	if err = RestoreSnapshot(gamePath, snapshots[1].ID); err != nil {
+		t.Fatalf("Failed to restore snapshot with error:\n%v", err)
+	}
+	restored, err = os.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(restored, edited) {
+		t.Errorf("Restoring the second snapshot did not bring back the edits made before it")
+	}
+	t.Log("Second snapshot restored...")
+
+	t.Log("Backups Passed!")
+}
+
+func TestRestoreRejectsCorruptedBackup(t *testing.T) {
+	t.Log("Testing corrupted backups...")
+
+	file := openFixture(t, "Strings_Test.csv")
+	gamePath := filepath.Dir(filepath.Dir(file.Name()))
+	sheet := &StringSheet{File: file}
+	if err := sheet.Parse(); err != nil {
+		t.Fatalf("Failed to parse with error:\n%v", err)
+	}
+
+	sheet.Strings[0].Strings[0].String = "Begin"
+	if err := sheet.Update(); err != nil {
+		t.Fatalf("Failed to update with error:\n%v", err)
+	}
+	edited, err := os.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshots, err := ListSnapshots(gamePath)
+	if err != nil || len(snapshots) != 1 {
+		t.Fatalf("Expected a single snapshot, got %+v (%v)", snapshots, err)
+	}
+
+	copyPath := filepath.Join(BackupDir, snapshots[0].ID, snapshots[0].Files[0].Copy)
+	writeTestFile(t, copyPath, "garbage")
+
+	if err = RestoreSnapshot(gamePath, snapshots[0].ID); err == nil {
+		t.Errorf("Restoring a corrupted backup should fail")
+	}
+
+	current, err := os.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(current, edited) {
+		t.Errorf("A failed restore should not touch the game files")
+	}
+
+	t.Log("Corrupted backups Passed!")
+}
+
+func TestBackupDirRelativeToGame(t *testing.T) {
+	t.Log("Testing relative backup folders...")
+
+	file := openFixture(t, "Names_Test.csv")
+	gamePath := filepath.Dir(filepath.Dir(file.Name()))
+
+	BackupDir = "Backups"
+	StartSnapshot()
+
+	sheet := &NameSheet{File: file}
+	if err := sheet.Parse(); err != nil {
+		t.Fatalf("Failed to parse with error:\n%v", err)
+	}
+	sheet.Strings[0].Strings[0].String = "Sword?"
+	if err := sheet.Update(); err != nil {
+		t.Fatalf("Failed to update with error:\n%v", err)
+	}
+
+	snapshots, err := ListSnapshots(gamePath)
+	if err != nil || len(snapshots) != 1 {
+		t.Fatalf("Expected a single snapshot, got %+v (%v)", snapshots, err)
+	}
+	if _, err = os.Stat(filepath.Join(gamePath, "Backups", snapshots[0].ID, backupManifestName)); err != nil {
+		t.Errorf("Expected the snapshot to be in the game folder, got %v", err)
+	}
+
+	t.Log("Relative backup folders Passed!")
+}
+
+func TestRestoreIsAllOrNothing(t *testing.T) {
+	t.Log("Testing failed restores...")
+
+	names := openFixture(t, "Names_Test.csv")
+	strings := openFixture(t, "Strings_Test.csv")
+	gamePath := filepath.Dir(filepath.Dir(names.Name()))
+
+	nameSheet := &NameSheet{File: names}
+	stringSheet := &StringSheet{File: strings}
+	for _, sheet := range []TranslationFile{nameSheet, stringSheet} {
+		if err := sheet.Parse(); err != nil {
+			t.Fatalf("Failed to parse with error:\n%v", err)
+		}
+	}
+
+	nameSheet.Strings[0].Strings[0].String = "Sword?"
+	stringSheet.Strings[0].Strings[0].String = "Begin"
+	for _, sheet := range []TranslationFile{nameSheet, stringSheet} {
+		if err := sheet.Update(); err != nil {
+			t.Fatalf("Failed to update with error:\n%v", err)
+		}
+	}
+	edited, err := os.ReadFile(names.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshots, err := ListSnapshots(gamePath)
+	if err != nil || len(snapshots) != 1 || len(snapshots[0].Files) != 2 {
+		t.Fatalf("Expected a single snapshot with both files, got %+v (%v)", snapshots, err)
+	}
+
+	// The strings sheet comes after the names one, and can't be written anymore
+	if err = os.Remove(strings.Name()); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Mkdir(strings.Name(), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = RestoreSnapshot(gamePath, snapshots[0].ID); err == nil {
+		t.Errorf("Restoring over a folder should fail")
+	}
+
+	current, err := os.ReadFile(names.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(current, edited) {
+		t.Errorf("A failed restore should not restore any file")
+	}
+
+	t.Log("Failed restores Passed!")
+}
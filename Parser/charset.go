@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// How many times a rune is used
+type CharCount struct {
+	Rune  rune
+	Count int
+}
+
+func (c CharCount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Char      string `json:"char"`
+		Codepoint string `json:"codepoint"`
+		Count     int    `json:"count"`
+	}{string(c.Rune), fmt.Sprintf("%U", c.Rune), c.Count})
+}
+
+// Every rune a language uses, sorted
+type CharacterSet struct {
+	Language   string      `json:"language"`
+	Characters []CharCount `json:"characters"`
+}
+
+// The characters every language of LanguageEnable.csv uses across every sheet and dialogue, in the same order.
+// The native name of the language is counted too, since the game shows it.
+// Tags and control characters (like line breaks) are left out, they are never drawn.
+func CharacterSets(lfs LanguageFiles) []CharacterSet {
+	counts := make([]map[rune]int, len(lfs.Languages.Languages))
+	for i := range counts {
+		counts[i] = make(map[rune]int)
+	}
+
+	count := func(language int, s string) {
+		for _, r := range renderedRunes(s) {
+			if !unicode.IsControl(r) {
+				counts[language][r]++
+			}
+		}
+	}
+
+	for i, language := range lfs.Languages.Languages {
+		count(i, language.NativeName)
+	}
+	for _, entry := range lfs.Entries() {
+		for i, language := range lfs.Languages.Languages {
+			if value, ok := entry.Get(language.Name); ok {
+				count(i, value)
+			}
+		}
+	}
+
+	sets := make([]CharacterSet, len(counts))
+	for i, language := range lfs.Languages.Languages {
+		characters := make([]CharCount, 0, len(counts[i]))
+		for r, n := range counts[i] {
+			characters = append(characters, CharCount{r, n})
+		}
+		sort.Slice(characters, func(a, b int) bool { return characters[a].Rune < characters[b].Rune })
+
+		sets[i] = CharacterSet{language.Name, characters}
+	}
+
+	return sets
+}
+
+// Writes the sets as plain text, a line per character with its code point, the character and its count
+func WriteCharacterSets(w io.Writer, sets []CharacterSet) error {
+	for i, set := range sets {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+
+		if _, err := fmt.Fprintf(w, "%v: %d characters\n", set.Language, len(set.Characters)); err != nil {
+			return err
+		}
+		for _, c := range set.Characters {
+			char := string(c.Rune)
+			if unicode.IsSpace(c.Rune) {
+				char = fmt.Sprintf("%q", c.Rune)
+			}
+
+			if _, err := fmt.Fprintf(w, "%U\t%v\t%d\n", c.Rune, char, c.Count); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// The set as a list of code point ranges, one per line (e.g. U+0041-005A),
+// which font subsetters like pyftsubset take through --unicodes-file
+func (cs CharacterSet) SubsetList() string {
+	var list strings.Builder
+
+	for i := 0; i < len(cs.Characters); {
+		start := cs.Characters[i].Rune
+		end := start
+		for i++; i < len(cs.Characters) && cs.Characters[i].Rune == end+1; i++ {
+			end++
+		}
+
+		if start == end {
+			fmt.Fprintf(&list, "U+%04X\n", start)
+		} else {
+			fmt.Fprintf(&list, "U+%04X-%04X\n", start, end)
+		}
+	}
+
+	return list.String()
+}
@@ -0,0 +1,59 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCharacterSets(t *testing.T) {
+	t.Log("Testing CharacterSets...")
+
+	gamePath := newTestGame(t)
+	writeTestFile(t, gamePath+"/Data/Strings_Menu.csv", "Key,English,Japanese,Chinese\r\n"+
+		"tags,<b>ABC</b>,エー\\nエー,\r\n")
+
+	gameFiles := parseTestGame(t, gamePath)
+
+	sets := CharacterSets(gameFiles)
+	if len(sets) != 3 || sets[0].Language != "English" || sets[1].Language != "Japanese" {
+		t.Fatalf("Expected a set per language in order, got %v", sets)
+	}
+
+	counts := make(map[rune]int)
+	for i, c := range sets[1].Characters {
+		counts[c.Rune] = c.Count
+		if i > 0 && sets[1].Characters[i-1].Rune >= c.Rune {
+			t.Errorf("Characters are not sorted: %v", sets[1].Characters)
+		}
+	}
+	// エ shows up once in every sheet but Strings_Menu, where it shows up twice
+	if counts['エ'] != len(gameFiles.Sheets)+1 || counts['日'] != 1 || counts['\\'] != 0 || counts['b'] != 0 {
+		t.Errorf("Wrong Japanese counts: %v", counts)
+	}
+	t.Log("Counted...")
+
+	var text bytes.Buffer
+	if err := WriteCharacterSets(&text, sets[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(text.String(), "English: ") || !strings.Contains(text.String(), "U+0020\t' '\t") {
+		t.Errorf("Unexpected text output:\n%v", text.String())
+	}
+
+	data, err := json.Marshal(sets[1].Characters[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"char":"。","codepoint":"U+3002","count":18}` {
+		t.Errorf("Unexpected JSON output: %s", data)
+	}
+
+	subset := CharacterSet{Characters: []CharCount{{'A', 1}, {'B', 1}, {'C', 1}, {'a', 1}, {'あ', 1}, {'い', 1}}}.SubsetList()
+	if subset != "U+0041-0043\nU+0061\nU+3042\nU+3044\n" {
+		t.Errorf("Unexpected subset list:\n%v", subset)
+	}
+
+	t.Log("CharacterSets Passed!")
+}
@@ -0,0 +1,358 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// encoding/csv is fine for reading, but it throws away everything needed to
+// write a file back the way the game shipped it (quoting, line endings, BOM, empty lines).
+// So we keep our own small reader that remembers the raw text of every field,
+// and a writer that reuses it for every field that did not change.
+
+const utf8BOM string = "\ufeff"
+
+// Everything needed to write a CSV file back byte-for-byte
+type csvLayout struct {
+	// Whether the file started with a UTF-8 BOM
+	bom bool
+
+	// Parsed value of every field, as it was read
+	values [][]string
+
+	// Raw text of every field, quotes included
+	raw [][]string
+
+	// Terminator of every record ("\r\n", "\n", or "" for a last line without one)
+	ends []string
+
+	// Terminator used for records that did not exist in the original file
+	lineEnding string
+
+	// Line every record starts at, starting at 1
+	lines []int
+}
+
+// Reads CSV data, keeping track of how it was written.
+// It is lenient in the same way LazyQuotes is,
+// and it does not require every record to have the same amount of fields.
+func readCSV(data []byte) ([][]string, *csvLayout, error) {
+	s := string(data)
+	layout := &csvLayout{
+		lineEnding: "\n",
+	}
+
+	if strings.HasPrefix(s, utf8BOM) {
+		layout.bom = true
+		s = s[len(utf8BOM):]
+	}
+
+	pos := 0
+	line := 1
+	for pos < len(s) {
+		var record, raws []string
+		recordStart := pos
+
+		for {
+			start := pos
+			var value string
+
+			if s[pos] == '"' {
+				var b strings.Builder
+				pos++
+				for {
+					i := strings.IndexByte(s[pos:], '"')
+					if i < 0 {
+						return nil, nil, &ParseError{
+							Row:    len(layout.values) + 1,
+							Line:   line,
+							Column: len(record) + 1,
+							Err:    errors.New("quoted field is never closed"),
+						}
+					}
+					b.WriteString(s[pos : pos+i])
+					pos += i + 1
+
+					// Escaped quote
+					if pos < len(s) && s[pos] == '"' {
+						b.WriteByte('"')
+						pos++
+						continue
+					}
+					break
+				}
+
+				// Anything after the closing quote is kept as is
+				for pos < len(s) && !isFieldEnd(s, pos) {
+					b.WriteByte(s[pos])
+					pos++
+				}
+				value = b.String()
+			} else {
+				for pos < len(s) && !isFieldEnd(s, pos) {
+					pos++
+				}
+				value = s[start:pos]
+			}
+
+			record = append(record, value)
+			raws = append(raws, s[start:pos])
+
+			if pos < len(s) && s[pos] == ',' {
+				pos++
+				// A trailing comma at the end of the data still means an empty field
+				if pos == len(s) {
+					record = append(record, "")
+					raws = append(raws, "")
+					break
+				}
+				continue
+			}
+			break
+		}
+
+		end := ""
+		if strings.HasPrefix(s[pos:], "\r\n") {
+			end = "\r\n"
+		} else if strings.HasPrefix(s[pos:], "\n") {
+			end = "\n"
+		}
+		pos += len(end)
+
+		layout.values = append(layout.values, record)
+		layout.raw = append(layout.raw, raws)
+		layout.ends = append(layout.ends, end)
+		layout.lines = append(layout.lines, line)
+		line += strings.Count(s[recordStart:pos], "\n")
+	}
+
+	for _, end := range layout.ends {
+		if end != "" {
+			layout.lineEnding = end
+			break
+		}
+	}
+
+	records := make([][]string, len(layout.values))
+	for i, record := range layout.values {
+		records[i] = append([]string(nil), record...)
+	}
+
+	return records, layout, nil
+}
+
+// Whether the field starting before pos ends at pos
+func isFieldEnd(s string, pos int) bool {
+	switch s[pos] {
+	case ',', '\n':
+		return true
+	case '\r':
+		return pos+1 < len(s) && s[pos+1] == '\n'
+	}
+	return false
+}
+
+// Writes the records back, reusing the original text of every field that did not change
+func writeCSV(records [][]string, layout *csvLayout) []byte {
+	if layout == nil {
+		layout = &csvLayout{lineEnding: "\n"}
+	}
+
+	var b bytes.Buffer
+	if layout.bom {
+		b.WriteString(utf8BOM)
+	}
+
+	for r, record := range records {
+		for c, value := range record {
+			if c > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(layout.field(r, c, value))
+		}
+		b.WriteString(layout.end(r, len(records)))
+	}
+
+	return b.Bytes()
+}
+
+// Raw text for a field
+func (l *csvLayout) field(r, c int, value string) string {
+	wasQuoted := false
+	if r < len(l.values) && c < len(l.values[r]) {
+		if l.values[r][c] == value {
+			return l.raw[r][c]
+		}
+		wasQuoted = strings.HasPrefix(l.raw[r][c], `"`)
+	}
+
+	return quoteField(value, wasQuoted)
+}
+
+// Quotes the value if it needs it, or if asked to
+func quoteField(value string, force bool) string {
+	if !force && !fieldNeedsQuotes(value) {
+		return value
+	}
+
+	return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+}
+
+func fieldNeedsQuotes(value string) bool {
+	if value == "" {
+		return false
+	}
+
+	return value[0] == ' ' || value[0] == '\t' || strings.ContainsAny(value, ",\"\r\n")
+}
+
+// Terminator for a record
+func (l *csvLayout) end(r, total int) string {
+	// Keep whatever the file ended with
+	if r == total-1 && len(l.ends) > 0 {
+		return l.ends[len(l.ends)-1]
+	}
+
+	if r < len(l.ends) && l.ends[r] != "" {
+		return l.ends[r]
+	}
+
+	return l.lineEnding
+}
+
+// Original text of an int field, as long as it still holds the same number.
+// This is what keeps something like "007" from coming back as "7".
+func (l *csvLayout) intField(r, c, value int) string {
+	if r < len(l.values) && c < len(l.values[r]) {
+		if original, err := strconv.Atoi(l.values[r][c]); err == nil && original == value {
+			return l.values[r][c]
+		}
+	}
+
+	return strconv.Itoa(value)
+}
+
+// Original record for an empty spacer row,
+// or a row of empty fields as wide as the header if there isn't one.
+func (l *csvLayout) spacer(r int) []string {
+	if r < len(l.values) && l.values[r][0] == "" {
+		return l.values[r]
+	}
+
+	if len(l.values) == 0 {
+		return []string{""}
+	}
+
+	return make([]string, len(l.values[0]))
+}
+
+// Original text of a bool field, as long as it still holds the same value.
+// New values are written the way the game writes them, as 1 or 0.
+func (l *csvLayout) boolField(r, c int, value bool) string {
+	if r < len(l.values) && c < len(l.values[r]) {
+		if original, err := strconv.ParseBool(l.values[r][c]); err == nil && original == value {
+			return l.values[r][c]
+		}
+	}
+
+	if value {
+		return "1"
+	}
+	return "0"
+}
+
+// Inserts an empty column before column c, with the given header in headerRow.
+// Rows too short to reach the column are left alone.
+func (l *csvLayout) insertColumn(c, headerRow int, header string) {
+	for r := range l.values {
+		if len(l.values[r]) < c {
+			continue
+		}
+
+		value, raw := "", ""
+		if r == headerRow {
+			value, raw = header, quoteField(header, false)
+		}
+
+		l.values[r] = insertString(l.values[r], c, value)
+		l.raw[r] = insertString(l.raw[r], c, raw)
+	}
+}
+
+// Removes column c from every row that has it
+func (l *csvLayout) removeColumn(c int) {
+	for r := range l.values {
+		if len(l.values[r]) <= c {
+			continue
+		}
+
+		l.values[r] = removeElement(l.values[r], c)
+		l.raw[r] = removeElement(l.raw[r], c)
+	}
+}
+
+// Moves column from to position to, in every row that has both
+func (l *csvLayout) moveColumn(from, to int) {
+	for r := range l.values {
+		if len(l.values[r]) <= from || len(l.values[r]) <= to {
+			continue
+		}
+
+		l.values[r] = moveElement(l.values[r], from, to)
+		l.raw[r] = moveElement(l.raw[r], from, to)
+	}
+}
+
+// Changes the header of column c, keeping its quoting
+func (l *csvLayout) setHeader(c int, value string) {
+	if len(l.values) == 0 || len(l.values[0]) <= c {
+		return
+	}
+
+	l.raw[0][c] = quoteField(value, strings.HasPrefix(l.raw[0][c], `"`))
+	l.values[0][c] = value
+}
+
+// A copy that can be changed without touching this one
+func (l *csvLayout) clone() *csvLayout {
+	copied := *l
+	copied.values = cloneRecords(l.values)
+	copied.raw = cloneRecords(l.raw)
+	copied.ends = append([]string(nil), l.ends...)
+	copied.lines = append([]int(nil), l.lines...)
+
+	return &copied
+}
+
+func cloneRecords(records [][]string) [][]string {
+	copied := make([][]string, len(records))
+	for i, record := range records {
+		copied[i] = append([]string(nil), record...)
+	}
+
+	return copied
+}
+
+func insertString(s []string, i int, value string) []string {
+	s = append(s, "")
+	copy(s[i+1:], s[i:])
+	s[i] = value
+
+	return s
+}
+
+func removeElement[T any](s []T, i int) []T {
+	return append(s[:i], s[i+1:]...)
+}
+
+func moveElement[T any](s []T, from, to int) []T {
+	value := s[from]
+	s = removeElement(s, from)
+	s = append(s, value)
+	copy(s[to+1:], s[to:])
+	s[to] = value
+
+	return s
+}
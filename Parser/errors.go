@@ -0,0 +1,96 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Where and why a file could not be parsed.
+// Every Parse returns one of these, get it with errors.As.
+type ParseError struct {
+	Path string
+	Type FileType
+
+	// Record of the CSV file, starting at 1 with the header. 0 if the problem is with the whole file.
+	Row int
+
+	// Line of the file the record starts at, which differs from Row once a field spans several lines.
+	// 0 if unknown.
+	Line int
+
+	// Field of the record, starting at 1. 0 if the problem is with the whole record.
+	Column int
+
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	location := e.Path
+	if location == "" {
+		location = "<unknown file>"
+	}
+	if e.Row > 0 {
+		location += fmt.Sprintf(", row %d", e.Row)
+	}
+	if e.Line > 0 && e.Line != e.Row {
+		location += fmt.Sprintf(" (line %d)", e.Line)
+	}
+	if e.Column > 0 {
+		location += fmt.Sprintf(", column %d", e.Column)
+	}
+
+	return fmt.Sprintf("%v (%v): %v", location, e.Type, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func (ft FileType) String() string {
+	switch ft {
+	case TypeName:
+		return "Names sheet"
+	case TypeDescription:
+		return "Descriptions sheet"
+	case TypeTitle:
+		return "Titles sheet"
+	case TypeString:
+		return "Strings sheet"
+	case TypeStringEnum:
+		return "StringEnum sheet"
+	case TypeDialogue:
+		return "Dialogue"
+	case TypeLanguage:
+		return "LanguageEnable"
+	}
+
+	return fmt.Sprintf("FileType(%d)", uint8(ft))
+}
+
+// Makes sure err is a *ParseError pointing at the file.
+// Errors that already are one just get the file filled in, and the line if the layout knows it.
+func parseFailure(err error, path string, fileType FileType, layout *csvLayout) error {
+	if err == nil {
+		return nil
+	}
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		parseErr = &ParseError{Err: err}
+	}
+
+	if parseErr.Path == "" {
+		parseErr.Path = path
+		parseErr.Type = fileType
+	}
+	if parseErr.Line == 0 && layout != nil && parseErr.Row > 0 && parseErr.Row <= len(layout.lines) {
+		parseErr.Line = layout.lines[parseErr.Row-1]
+	}
+
+	return parseErr
+}
+
+// A *ParseError for a file type that has no File to work on
+func noFile(fileType FileType) error {
+	return &ParseError{Type: fileType, Err: ErrNoFile}
+}
@@ -0,0 +1,398 @@
+package parser
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// The characters a TrueType or OpenType font has glyphs for, read from its cmap table.
+// Nothing else of the font is looked at.
+type Font struct {
+	Path string
+
+	// Sorted, non-overlapping ranges of covered runes, both ends included
+	ranges [][2]rune
+}
+
+// Reads the cmap of a .ttf, .otf or .ttc file. For collections, the first font is used.
+func LoadFont(path string) (*Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	font, err := ParseFont(data)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	font.Path = path
+
+	return font, nil
+}
+
+// Reads the cmap of font data
+func ParseFont(data []byte) (*Font, error) {
+	if len(data) < 12 {
+		return nil, errors.New("Font is too short")
+	}
+
+	offset := uint32(0)
+	if string(data[:4]) == "ttcf" {
+		if len(data) < 16 {
+			return nil, errors.New("Font collection is too short")
+		}
+		offset = binary.BigEndian.Uint32(data[12:])
+	}
+
+	cmap, err := fontTable(data, offset, "cmap")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCmap(cmap)
+}
+
+// Finds a table in the table directory starting at offset
+func fontTable(data []byte, offset uint32, tag string) ([]byte, error) {
+	if uint64(offset)+12 > uint64(len(data)) {
+		return nil, errors.New("Font table directory is out of bounds")
+	}
+
+	switch version := string(data[offset : offset+4]); version {
+	case "\x00\x01\x00\x00", "OTTO", "true":
+	default:
+		return nil, fmt.Errorf("Not a TrueType or OpenType font (version %q)", version)
+	}
+
+	tables := int(binary.BigEndian.Uint16(data[offset+4:]))
+	for i := 0; i < tables; i++ {
+		record := uint64(offset) + 12 + uint64(i)*16
+		if record+16 > uint64(len(data)) {
+			return nil, errors.New("Font table directory is out of bounds")
+		}
+
+		if string(data[record:record+4]) != tag {
+			continue
+		}
+
+		start := uint64(binary.BigEndian.Uint32(data[record+8:]))
+		length := uint64(binary.BigEndian.Uint32(data[record+12:]))
+		if start+length > uint64(len(data)) {
+			return nil, fmt.Errorf("Font table %v is out of bounds", tag)
+		}
+
+		return data[start : start+length], nil
+	}
+
+	return nil, fmt.Errorf("Font has no %v table", tag)
+}
+
+// Puts together every Unicode subtable of the cmap
+func parseCmap(cmap []byte) (*Font, error) {
+	if len(cmap) < 4 {
+		return nil, errors.New("Font cmap is too short")
+	}
+
+	font := &Font{}
+	found := false
+
+	subtables := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < subtables; i++ {
+		record := 4 + i*8
+		if record+8 > len(cmap) {
+			return nil, errors.New("Font cmap is out of bounds")
+		}
+
+		platform := binary.BigEndian.Uint16(cmap[record:])
+		encoding := binary.BigEndian.Uint16(cmap[record+2:])
+		// Unicode, or Windows' Unicode BMP and full repertoire
+		if platform != 0 && !(platform == 3 && (encoding == 1 || encoding == 10)) {
+			continue
+		}
+
+		start := int(binary.BigEndian.Uint32(cmap[record+4:]))
+		if start+2 > len(cmap) {
+			return nil, errors.New("Font cmap subtable is out of bounds")
+		}
+
+		ranges, ok, err := parseCmapSubtable(cmap[start:])
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			font.ranges = append(font.ranges, ranges...)
+			found = true
+		}
+	}
+
+	if !found {
+		return nil, errors.New("Font has no Unicode cmap it can be read from")
+	}
+
+	font.ranges = mergeRanges(font.ranges)
+	return font, nil
+}
+
+// Covered ranges of a subtable, and whether its format is one we read
+func parseCmapSubtable(table []byte) ([][2]rune, bool, error) {
+	outOfBounds := errors.New("Font cmap subtable is out of bounds")
+	ranges := make([][2]rune, 0)
+	add := func(r rune) {
+		if len(ranges) > 0 && ranges[len(ranges)-1][1] == r-1 {
+			ranges[len(ranges)-1][1] = r
+			return
+		}
+		ranges = append(ranges, [2]rune{r, r})
+	}
+
+	switch format := binary.BigEndian.Uint16(table); format {
+	case 0:
+		// Byte encoding table
+		if len(table) < 6+256 {
+			return nil, false, outOfBounds
+		}
+		for c := 0; c < 256; c++ {
+			if table[6+c] != 0 {
+				add(rune(c))
+			}
+		}
+
+	case 4:
+		// Segment mapping to delta values, the usual one for the BMP
+		if len(table) < 14 {
+			return nil, false, outOfBounds
+		}
+		segments := int(binary.BigEndian.Uint16(table[6:])) / 2
+		ends := 14
+		starts := ends + segments*2 + 2
+		deltas := starts + segments*2
+		rangeOffsets := deltas + segments*2
+		if rangeOffsets+segments*2 > len(table) {
+			return nil, false, outOfBounds
+		}
+
+		for s := 0; s < segments; s++ {
+			end := int(binary.BigEndian.Uint16(table[ends+s*2:]))
+			start := int(binary.BigEndian.Uint16(table[starts+s*2:]))
+			delta := int(binary.BigEndian.Uint16(table[deltas+s*2:]))
+			rangeOffset := int(binary.BigEndian.Uint16(table[rangeOffsets+s*2:]))
+
+			for c := start; c <= end && c != 0xFFFF; c++ {
+				glyph := 0
+				if rangeOffset == 0 {
+					glyph = (c + delta) & 0xFFFF
+				} else {
+					at := rangeOffsets + s*2 + rangeOffset + (c-start)*2
+					if at+2 > len(table) {
+						return nil, false, outOfBounds
+					}
+					if glyph = int(binary.BigEndian.Uint16(table[at:])); glyph != 0 {
+						glyph = (glyph + delta) & 0xFFFF
+					}
+				}
+
+				if glyph != 0 {
+					add(rune(c))
+				}
+			}
+		}
+
+	case 6:
+		// Trimmed table mapping
+		if len(table) < 10 {
+			return nil, false, outOfBounds
+		}
+		first := int(binary.BigEndian.Uint16(table[6:]))
+		count := int(binary.BigEndian.Uint16(table[8:]))
+		if 10+count*2 > len(table) {
+			return nil, false, outOfBounds
+		}
+		for i := 0; i < count; i++ {
+			if binary.BigEndian.Uint16(table[10+i*2:]) != 0 {
+				add(rune(first + i))
+			}
+		}
+
+	case 12:
+		// Segmented coverage, for fonts going past the BMP
+		if len(table) < 16 {
+			return nil, false, outOfBounds
+		}
+		groups := int(binary.BigEndian.Uint32(table[12:]))
+		if groups < 0 || 16+groups*12 > len(table) {
+			return nil, false, outOfBounds
+		}
+		for g := 0; g < groups; g++ {
+			start := rune(binary.BigEndian.Uint32(table[16+g*12:]))
+			end := rune(binary.BigEndian.Uint32(table[20+g*12:]))
+			glyph := binary.BigEndian.Uint32(table[24+g*12:])
+
+			// Only the first character of a group can land on the missing glyph
+			if glyph == 0 {
+				start++
+			}
+			if start <= end {
+				ranges = append(ranges, [2]rune{start, end})
+			}
+		}
+
+	default:
+		return nil, false, nil
+	}
+
+	return ranges, true, nil
+}
+
+func mergeRanges(ranges [][2]rune) [][2]rune {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+
+	merged := make([][2]rune, 0, len(ranges))
+	for _, r := range ranges {
+		if last := len(merged) - 1; last >= 0 && r[0] <= merged[last][1]+1 {
+			merged[last][1] = max(merged[last][1], r[1])
+			continue
+		}
+		merged = append(merged, r)
+	}
+
+	return merged
+}
+
+// Whether the font has a glyph for the rune
+func (f *Font) HasRune(r rune) bool {
+	i := sort.Search(len(f.ranges), func(i int) bool { return f.ranges[i][1] >= r })
+	return i < len(f.ranges) && f.ranges[i][0] <= r
+}
+
+// Where a rune is used
+type RunePlace struct {
+	Sheet string `json:"sheet"`
+	Key   string `json:"key"`
+}
+
+// A rune a language uses that its font has no glyph for
+type MissingGlyph struct {
+	Language string      `json:"language"`
+	Rune     rune        `json:"rune"`
+	Places   []RunePlace `json:"places"`
+}
+
+func (m MissingGlyph) String() string {
+	places := make([]string, len(m.Places))
+	for i, place := range m.Places {
+		places[i] = place.Sheet + " " + place.Key
+	}
+
+	return fmt.Sprintf("%v: %q (%U) is not in the font, used in %v", m.Language, m.Rune, m.Rune, strings.Join(places, ", "))
+}
+
+// Runes that end up on screen, tags are left out since the game doesn't draw them
+func renderedRunes(s string) []rune {
+	runes := make([]rune, 0, len(s))
+	offset := 0
+	for _, token := range Tokens(s) {
+		runes = append(runes, []rune(s[offset:token.Offset])...)
+		if token.Kind == TokenFormat {
+			runes = append(runes, []rune(token.Text)...)
+		}
+		offset = token.Offset + len(token.Text)
+	}
+
+	return append(runes, []rune(s[offset:])...)
+}
+
+// Every rune of a language's strings that the font has no glyph for, in rune order.
+// Control characters, like line breaks, are skipped.
+func CheckGlyphCoverage(lfs LanguageFiles, lang string, font *Font) []MissingGlyph {
+	missing := make(map[rune]*MissingGlyph)
+
+	for _, entry := range lfs.Entries() {
+		value, ok := entry.Get(lang)
+		if !ok {
+			continue
+		}
+
+		for _, r := range renderedRunes(value) {
+			if unicode.IsControl(r) || font.HasRune(r) {
+				continue
+			}
+
+			glyph, ok := missing[r]
+			if !ok {
+				glyph = &MissingGlyph{Language: lang, Rune: r}
+				missing[r] = glyph
+			}
+
+			place := RunePlace{entry.Sheet, entry.Key}
+			if len(glyph.Places) == 0 || glyph.Places[len(glyph.Places)-1] != place {
+				glyph.Places = append(glyph.Places, place)
+			}
+		}
+	}
+
+	glyphs := make([]MissingGlyph, 0, len(missing))
+	for _, glyph := range missing {
+		glyphs = append(glyphs, *glyph)
+	}
+	sort.Slice(glyphs, func(i, j int) bool { return glyphs[i].Rune < glyphs[j].Rune })
+
+	return glyphs
+}
+
+// Finds the file of a font in a folder, by its name with a .ttf, .otf or .ttc extension, ignoring case
+func FindFontFile(dir, fontName string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		ext := strings.ToLower(filepath.Ext(name))
+		if entry.IsDir() || (ext != ".ttf" && ext != ".otf" && ext != ".ttc") {
+			continue
+		}
+
+		if strings.EqualFold(strings.TrimSuffix(name, filepath.Ext(name)), fontName) {
+			return filepath.Join(dir, name), nil
+		}
+	}
+
+	return "", fmt.Errorf("Font %v was not found in %v", fontName, dir)
+}
+
+// Checks every language with an external font against its font file, looked up in fontDir by FontName.
+// Languages whose font can't be found or read are reported together in the error,
+// the others are still checked.
+func CheckExternalFonts(lfs LanguageFiles, fontDir string) ([]MissingGlyph, error) {
+	glyphs := make([]MissingGlyph, 0)
+	errs := make([]error, 0)
+
+	for _, language := range lfs.Languages.Languages {
+		if !language.ExternalFont || language.FontName == "" {
+			continue
+		}
+
+		path, err := FindFontFile(fontDir, language.FontName)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Language %v: %w", language.Name, err))
+			continue
+		}
+
+		font, err := LoadFont(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Language %v: %w", language.Name, err))
+			continue
+		}
+
+		glyphs = append(glyphs, CheckGlyphCoverage(lfs, language.Name, font)...)
+	}
+
+	return glyphs, errors.Join(errs...)
+}
@@ -0,0 +1,131 @@
+package parser
+
+import (
+	"encoding/binary"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Builds a font with nothing but a cmap: a format 4 subtable for the BMP ranges and a format 12 one for the rest
+func testFont(bmp [][2]uint16, full [][2]uint32) []byte {
+	u16 := func(b []byte, v uint16) []byte { return binary.BigEndian.AppendUint16(b, v) }
+	u32 := func(b []byte, v uint32) []byte { return binary.BigEndian.AppendUint32(b, v) }
+
+	// Format 4, ending with the required 0xFFFF segment
+	bmp = append(bmp, [2]uint16{0xFFFF, 0xFFFF})
+	segments := uint16(len(bmp))
+	format4 := u16(nil, 4)
+	format4 = u16(format4, 16+8*segments)
+	format4 = u16(format4, 0)
+	format4 = u16(format4, segments*2)
+	format4 = append(format4, make([]byte, 6)...)
+	for _, r := range bmp {
+		format4 = u16(format4, r[1])
+	}
+	format4 = u16(format4, 0)
+	for _, r := range bmp {
+		format4 = u16(format4, r[0])
+	}
+	for _, r := range bmp {
+		// Glyphs start at 1, 0 is the missing glyph
+		format4 = u16(format4, 1-r[0])
+	}
+	for range bmp {
+		format4 = u16(format4, 0)
+	}
+
+	format12 := u16(nil, 12)
+	format12 = u16(format12, 0)
+	format12 = u32(format12, uint32(16+12*len(full)))
+	format12 = u32(format12, 0)
+	format12 = u32(format12, uint32(len(full)))
+	for _, r := range full {
+		format12 = u32(format12, r[0])
+		format12 = u32(format12, r[1])
+		format12 = u32(format12, 100)
+	}
+
+	// Header and the two encoding records, Windows Unicode BMP and full repertoire
+	cmap := u16(nil, 0)
+	cmap = u16(cmap, 2)
+	cmap = u16(cmap, 3)
+	cmap = u16(cmap, 1)
+	cmap = u32(cmap, 20)
+	cmap = u16(cmap, 3)
+	cmap = u16(cmap, 10)
+	cmap = u32(cmap, uint32(20+len(format4)))
+	cmap = append(append(cmap, format4...), format12...)
+
+	font := u32(nil, 0x00010000)
+	font = u16(font, 1)
+	font = append(font, make([]byte, 6)...)
+	font = append(font, "cmap"...)
+	font = u32(font, 0)
+	font = u32(font, 28)
+	font = u32(font, uint32(len(cmap)))
+
+	return append(font, cmap...)
+}
+
+func TestParseFont(t *testing.T) {
+	t.Log("Testing ParseFont...")
+
+	font, err := ParseFont(testFont([][2]uint16{{'A', 'Z'}, {'a', 'z'}}, [][2]uint32{{0x1F600, 0x1F64F}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range "AMZamz😀🙏" {
+		if !font.HasRune(r) {
+			t.Errorf("Font should have %q", r)
+		}
+	}
+	for _, r := range "@[`{0 あ🚀" {
+		if font.HasRune(r) {
+			t.Errorf("Font shouldn't have %q", r)
+		}
+	}
+
+	if _, err = ParseFont([]byte("definitely not a font")); err == nil {
+		t.Error("Parsed something that isn't a font")
+	}
+
+	t.Log("ParseFont Passed!")
+}
+
+func TestCheckExternalFonts(t *testing.T) {
+	t.Log("Testing CheckExternalFonts...")
+
+	_, gameFiles := openTestGame(t)
+
+	// Japanese gets a font with the basics and エー, Chinese doesn't get one at all
+	fontDir := t.TempDir()
+	if err := os.WriteFile(fontDir+"/notosansjp.TTF", testFont([][2]uint16{{' ', '~'}, {'エ', 'エ'}, {'ー', 'ー'}}, nil), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	glyphs, err := CheckExternalFonts(gameFiles, fontDir)
+	if err == nil || !strings.Contains(err.Error(), "NotoSansSC") {
+		t.Errorf("Expected the Chinese font to be missing, got %v", err)
+	}
+
+	runes := ""
+	for _, glyph := range glyphs {
+		t.Log(glyph)
+		if glyph.Language != "Japanese" {
+			t.Errorf("Expected only Japanese to be checked, got %v", glyph)
+		}
+		runes += string(glyph.Rune)
+	}
+	if runes != "。あこじちにねはゃんビ" {
+		t.Errorf("Expected the missing runes in order, got %q", runes)
+	}
+
+	last := glyphs[len(glyphs)-1]
+	if len(last.Places) != len(gameFiles.Sheets) || last.Places[0] != (RunePlace{"Data/Names_HBS.csv", "HBS_b"}) {
+		t.Errorf("Expected ビ to be found once in every sheet, got %v", last.Places)
+	}
+
+	t.Log("CheckExternalFonts Passed!")
+}
@@ -0,0 +1,333 @@
+package parser
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+)
+
+// Name of a translation file relative to the game folder, like Data/Names_HBS.csv or Dialog/birds.csv.
+// This is how sheets are referred to everywhere outside of this package.
+func SheetID(tf TranslationFile) string {
+	v, err := viewSheet(tf)
+	if err != nil || v.file == nil {
+		return ""
+	}
+
+	return sheetPathID(v.file.Name())
+}
+
+// SheetID of any path, for files that aren't parsed
+func sheetPathID(path string) string {
+	return filepath.ToSlash(filepath.Join(filepath.Base(filepath.Dir(path)), filepath.Base(path)))
+}
+
+// A row of any translation file, seen the same way whatever the file type is
+type Entry struct {
+	// SheetID of the file it is in
+	Sheet string
+	Type  FileType
+
+	// Key of the row. Dialogue lines don't have one, so they use their row number in the file,
+	// starting at 2 right after the header, the same way ParseError counts rows.
+	Key string
+
+	// Index of the row in the file's Strings
+	Row int
+
+	// Changes made through here end up in the file
+	Translations *[]Translation
+
+	// Set only for rows of Name, Description and Title sheets
+	KeyLevel *KeyLevelStrings
+
+	// Set only for dialogue lines
+	Dialogue *DialogueStrings
+
+	// Language of every column, as named in the header
+	Languages []string
+
+	// Column of every language, shared by every entry of the file
+	columns map[string]int
+
+	// Layout of the file, to get back the text of the fields
+	layout **csvLayout
+}
+
+// Every row with text in a translation file, spacers are skipped
+func FileEntries(tf TranslationFile) []Entry {
+	v, err := viewSheet(tf)
+	if err != nil || *v.layout == nil || len((*v.layout).values) == 0 {
+		return nil
+	}
+
+	sheet := SheetID(tf)
+	languages := v.languages()
+	entries := make([]Entry, 0)
+
+	// If a language shows up twice, the first column wins
+	columns := make(map[string]int, len(languages))
+	for i, lang := range languages {
+		if _, ok := columns[lang]; !ok {
+			columns[lang] = i
+		}
+	}
+
+	keyLevel := func(fileType FileType, rows []KeyLevelStrings) {
+		for i := range rows {
+			if rows[i].Key == "" {
+				continue
+			}
+			entries = append(entries, Entry{sheet, fileType, rows[i].Key, i, &rows[i].Strings, &rows[i], nil, languages, columns, v.layout})
+		}
+	}
+	key := func(fileType FileType, rows []KeyStrings) {
+		for i := range rows {
+			if rows[i].Key == "" {
+				continue
+			}
+			entries = append(entries, Entry{sheet, fileType, rows[i].Key, i, &rows[i].Strings, nil, nil, languages, columns, v.layout})
+		}
+	}
+
+	switch f := tf.(type) {
+	case *NameSheet:
+		keyLevel(TypeName, f.Strings)
+	case *DescriptionSheet:
+		keyLevel(TypeDescription, f.Strings)
+	case *TitleSheet:
+		keyLevel(TypeTitle, f.Strings)
+	case *StringSheet:
+		key(TypeString, f.Strings)
+	case *StringEnumSheet:
+		key(TypeStringEnum, f.Strings)
+	case *DialogueFile:
+		for i := range f.Strings {
+			if f.Strings[i].FlagScript == nil && f.Strings[i].ExpressionVar0 == nil {
+				continue
+			}
+			entries = append(entries, Entry{sheet, TypeDialogue, strconv.Itoa(i + 2), i, &f.Strings[i].Translations, nil, &f.Strings[i], languages, columns, v.layout})
+		}
+	}
+
+	return entries
+}
+
+// Every row with text, sheets first and then dialogues, in the order they were loaded
+func (lfs *LanguageFiles) Entries() []Entry {
+	entries := make([]Entry, 0)
+
+	for _, tf := range lfs.Sheets {
+		entries = append(entries, FileEntries(tf)...)
+	}
+	for _, tf := range lfs.Dialogues {
+		entries = append(entries, FileEntries(tf)...)
+	}
+
+	return entries
+}
+
+// Column of a language, or -1 if the entry doesn't have it
+func (e *Entry) column(lang string) int {
+	// Entries made outside of FileEntries don't have the map
+	if e.columns == nil {
+		return indexOf(e.Languages, lang)
+	}
+
+	if column, ok := e.columns[lang]; ok {
+		return column
+	}
+	return -1
+}
+
+// The text of an entry in a language, and whether the entry has that language at all
+func (e *Entry) Get(lang string) (string, bool) {
+	column := e.column(lang)
+	if column < 0 {
+		return "", false
+	}
+	if column >= len(*e.Translations) {
+		return "", true
+	}
+
+	return (*e.Translations)[column].String, true
+}
+
+// Type, Flag and Expression of a dialogue line the way they are written in the file,
+// so something like 007 doesn't turn into 7
+func (e *Entry) DialogueFields() []string {
+	if e.Dialogue == nil {
+		return nil
+	}
+
+	layout := &csvLayout{}
+	if e.layout != nil && *e.layout != nil {
+		layout = *e.layout
+	}
+
+	// The header is the first row in the file
+	row := e.Row + 1
+	return []string{
+		layout.intField(row, 0, e.Dialogue.Type),
+		layout.intOrStringField(row, 1, e.Dialogue.FlagScript),
+		layout.intOrStringField(row, 2, e.Dialogue.ExpressionVar0),
+	}
+}
+
+// Changes the text of an entry in a language
+func (e *Entry) Set(lang, value string) error {
+	column := e.column(lang)
+	if column < 0 {
+		return fmt.Errorf("%v has no %v column", e.Sheet, lang)
+	}
+
+	padTranslations(e.Translations, e.Languages)
+	(*e.Translations)[column].String = value
+
+	return nil
+}
+
+// A string found through the Index
+type IndexedString struct {
+	Sheet  string
+	Key    string
+	String string
+}
+
+// Constant time lookups over every parsed sheet.
+// It points straight into the sheets, so anything changed through Set is saved by their Update (or by Save).
+// Build a new one after adding, removing or moving languages, or after parsing again.
+type Index struct {
+	files   map[string]TranslationFile
+	entries map[string]map[string]*Entry
+
+	// Every entry of each sheet, in file order
+	rows map[string][]*Entry
+
+	// Sheets in load order, for iterating
+	order []string
+
+	// Sheets changed through Set since the last Save
+	dirty map[string]bool
+}
+
+func NewIndex(lfs *LanguageFiles) *Index {
+	idx := &Index{
+		files:   make(map[string]TranslationFile),
+		entries: make(map[string]map[string]*Entry),
+		rows:    make(map[string][]*Entry),
+		order:   make([]string, 0),
+		dirty:   make(map[string]bool),
+	}
+
+	for _, tf := range append(append([]TranslationFile(nil), lfs.Sheets...), lfs.Dialogues...) {
+		sheet := SheetID(tf)
+		idx.files[sheet] = tf
+		idx.order = append(idx.order, sheet)
+
+		keys := make(map[string]*Entry)
+		entries := FileEntries(tf)
+		for i := range entries {
+			idx.rows[sheet] = append(idx.rows[sheet], &entries[i])
+
+			// If a key shows up twice, the first one wins, like in the game
+			if _, ok := keys[entries[i].Key]; !ok {
+				keys[entries[i].Key] = &entries[i]
+			}
+		}
+		idx.entries[sheet] = keys
+	}
+
+	return idx
+}
+
+// The entry for a key, or nil
+func (idx *Index) Entry(sheet, key string) *Entry {
+	return idx.entries[sheet][key]
+}
+
+// The text of a key in a language, and whether it exists at all
+func (idx *Index) Get(sheet, key, lang string) (string, bool) {
+	entry := idx.Entry(sheet, key)
+	if entry == nil {
+		return "", false
+	}
+
+	return entry.Get(lang)
+}
+
+// Changes the text of a key in a language.
+// Nothing is written until Save (or the sheet's Update) is called.
+func (idx *Index) Set(sheet, key, lang, value string) error {
+	entry := idx.Entry(sheet, key)
+	if entry == nil {
+		return fmt.Errorf("%v has no key %v", sheet, key)
+	}
+
+	if err := entry.Set(lang, value); err != nil {
+		return err
+	}
+	idx.dirty[sheet] = true
+
+	return nil
+}
+
+// Every string in a language, in load order
+func (idx *Index) ByLanguage(lang string) []IndexedString {
+	strings := make([]IndexedString, 0)
+
+	for _, sheet := range idx.order {
+		for _, entry := range idx.rows[sheet] {
+			if value, ok := entry.Get(lang); ok {
+				strings = append(strings, IndexedString{sheet, entry.Key, value})
+			}
+		}
+	}
+
+	return strings
+}
+
+// Sheets changed through Set that haven't been saved yet
+func (idx *Index) Dirty() []string {
+	sheets := make([]string, 0, len(idx.dirty))
+	for _, sheet := range idx.order {
+		if idx.dirty[sheet] {
+			sheets = append(sheets, sheet)
+		}
+	}
+
+	return sheets
+}
+
+// Updates every sheet changed through Set, backing them up together in a new snapshot.
+// Either every one of them is written or none are.
+func (idx *Index) Save() error {
+	dirty := idx.Dirty()
+	writes := make([]pendingWrite, 0, len(dirty))
+	for _, sheet := range dirty {
+		v, err := viewSheet(idx.files[sheet])
+		if err != nil {
+			return err
+		}
+		if v.file == nil {
+			return noFile(v.fileType)
+		}
+
+		data, err := v.encode()
+		if err != nil {
+			return err
+		}
+		writes = append(writes, pendingWrite{path: v.file.Name(), data: data})
+	}
+
+	StartSnapshot()
+	if err := writeGameFiles(writes); err != nil {
+		return err
+	}
+
+	for _, sheet := range dirty {
+		delete(idx.dirty, sheet)
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package parser
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	t.Log("Testing the Index...")
+
+	gamePath, gameFiles := openTestGame(t)
+
+	idx := NewIndex(&gameFiles)
+
+	if value, ok := idx.Get("Data/Names_Item.csv", "Item_b", "English"); !ok || value != "B, Item" {
+		t.Errorf("Expected \"B, Item\", got %q (%v)", value, ok)
+	}
+	if value, ok := idx.Get("Dialog/frog.csv", "3", "Japanese"); !ok || value != "じゃあね。" {
+		t.Errorf("Expected the second dialogue line, got %q (%v)", value, ok)
+	}
+	if _, ok := idx.Get("Data/Names_Item.csv", "Item_b", "Klingon"); ok {
+		t.Error("Found a language that doesn't exist")
+	}
+	if _, ok := idx.Get("Data/Names_Item.csv", "", "English"); ok {
+		t.Error("Found a spacer row")
+	}
+	t.Log("Get works...")
+
+	entry := idx.Entry("Data/Names_Item.csv", "Item_b")
+	if entry == nil || entry.KeyLevel == nil || entry.KeyLevel.Level != 2 || entry.Row != 2 {
+		t.Errorf("Expected Item_b at row 2 with level 2, got %+v", entry)
+	}
+
+	chinese := idx.ByLanguage("Chinese")
+	if len(chinese) != len(gameFiles.Entries()) {
+		t.Errorf("Expected %d Chinese strings, got %d", len(gameFiles.Entries()), len(chinese))
+	}
+	if chinese[0].Sheet != "Data/Names_HBS.csv" || chinese[0].Key != "HBS_a" || chinese[0].String != "诶" {
+		t.Errorf("Expected the first Chinese string to be HBS_a, got %+v", chinese[0])
+	}
+	t.Log("Iterating by language works...")
+
+	if err := idx.Set("Data/Names_Item.csv", "Item_a", "Japanese", "アイテム"); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Set("Dialog/frog.csv", "2", "English", "Ribbit."); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Set("Data/Names_Item.csv", "Item_c", "Japanese", "?"); err == nil {
+		t.Error("Set a key that doesn't exist")
+	}
+	if err := idx.Set("Data/Names_Item.csv", "Item_a", "Klingon", "?"); err == nil {
+		t.Error("Set a language that doesn't exist")
+	}
+	if dirty := idx.Dirty(); len(dirty) != 2 || dirty[0] != "Data/Names_Item.csv" || dirty[1] != "Dialog/frog.csv" {
+		t.Errorf("Expected Names_Item and frog to be dirty, got %v", dirty)
+	}
+
+	if err := idx.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Dirty()) != 0 {
+		t.Error("Sheets are still dirty after saving")
+	}
+
+	data, err := os.ReadFile(gamePath + "/Data/Names_Item.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "Item_a,1,A Item,アイテム,诶\r\n") {
+		t.Errorf("Change didn't reach the sheet:\n%s", data)
+	}
+	data, err = os.ReadFile(gamePath + "/Dialog/frog.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "0,007,1,Ribbit.,") {
+		t.Errorf("Change didn't reach the dialogue:\n%s", data)
+	}
+
+	t.Log("Index Passed!")
+}
+
+func TestIndexSaveRollback(t *testing.T) {
+	t.Log("Testing a failed Index save...")
+
+	gamePath, gameFiles := openTestGame(t)
+	original, err := os.ReadFile(gamePath + "/Data/Names_Item.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idx := NewIndex(&gameFiles)
+	if err := idx.Set("Data/Names_Item.csv", "Item_a", "Japanese", "アイテム"); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Set("Dialog/frog.csv", "2", "English", "Ribbit."); err != nil {
+		t.Fatal(err)
+	}
+
+	// Writing fails once it gets to the dialogue
+	if err := os.Remove(gamePath + "/Dialog/frog.csv"); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Save(); err == nil {
+		t.Fatal("Saving should fail when a file can't be written")
+	}
+
+	data, err := os.ReadFile(gamePath + "/Data/Names_Item.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(original) {
+		t.Errorf("Names_Item was saved even though the save failed:\n%s", data)
+	}
+	if len(idx.Dirty()) != 2 {
+		t.Errorf("Expected both sheets to still be dirty, got %v", idx.Dirty())
+	}
+
+	t.Log("Failed Index save Passed!")
+}
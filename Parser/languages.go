@@ -0,0 +1,480 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Operations on the languages themselves, which touch LanguageEnable.csv and every translation file at once.
+// Everything is checked before anything changes, and all files are written in one go through writeGameFiles,
+// so a half-done operation never reaches the disk.
+// If anything fails, the files in memory are put back as they were too.
+
+// What every translation file looks like once its fixed columns are ignored
+type sheetView struct {
+	file     *os.File
+	fileType FileType
+	layout   **csvLayout
+
+	// Columns before the first language
+	fixed int
+
+	// Translations of every non-empty row
+	rows []*[]Translation
+
+	encode func() ([]byte, error)
+}
+
+func viewSheet(tf TranslationFile) (*sheetView, error) {
+	switch v := tf.(type) {
+	case *NameSheet:
+		return &sheetView{v.File, TypeName, &v.layout, 2, keyLevelRows(v.Strings), func() ([]byte, error) { return encodeKeyLevelStrings(v.Strings, v.layout) }}, nil
+	case *DescriptionSheet:
+		return &sheetView{v.File, TypeDescription, &v.layout, 2, keyLevelRows(v.Strings), func() ([]byte, error) { return encodeKeyLevelStrings(v.Strings, v.layout) }}, nil
+	case *TitleSheet:
+		return &sheetView{v.File, TypeTitle, &v.layout, 2, keyLevelRows(v.Strings), func() ([]byte, error) { return encodeKeyLevelStrings(v.Strings, v.layout) }}, nil
+	case *StringSheet:
+		return &sheetView{v.File, TypeString, &v.layout, 1, keyRows(v.Strings), func() ([]byte, error) { return encodeKeyStrings(v.Strings, v.layout) }}, nil
+	case *StringEnumSheet:
+		return &sheetView{v.File, TypeStringEnum, &v.layout, 1, keyRows(v.Strings), func() ([]byte, error) { return encodeKeyStrings(v.Strings, v.layout) }}, nil
+	case *DialogueFile:
+		return &sheetView{v.File, TypeDialogue, &v.layout, 3, dialogueRows(v.Strings), func() ([]byte, error) { return encodeDialogueStrings(v.Strings, v.layout) }}, nil
+	}
+
+	return nil, fmt.Errorf("Unknown translation file %T", tf)
+}
+
+func keyLevelRows(sheet []KeyLevelStrings) []*[]Translation {
+	rows := make([]*[]Translation, 0, len(sheet))
+	for i := range sheet {
+		if sheet[i].Key != "" {
+			rows = append(rows, &sheet[i].Strings)
+		}
+	}
+
+	return rows
+}
+
+func keyRows(sheet []KeyStrings) []*[]Translation {
+	rows := make([]*[]Translation, 0, len(sheet))
+	for i := range sheet {
+		if sheet[i].Key != "" {
+			rows = append(rows, &sheet[i].Strings)
+		}
+	}
+
+	return rows
+}
+
+func dialogueRows(sheet []DialogueStrings) []*[]Translation {
+	rows := make([]*[]Translation, 0, len(sheet))
+	for i := range sheet {
+		if sheet[i].FlagScript != nil || sheet[i].ExpressionVar0 != nil {
+			rows = append(rows, &sheet[i].Translations)
+		}
+	}
+
+	return rows
+}
+
+// Whether the file can be worked on at all
+func (v *sheetView) check() error {
+	if v.file == nil {
+		return noFile(v.fileType)
+	}
+	if *v.layout == nil || len((*v.layout).values) == 0 {
+		return fmt.Errorf("%v must be parsed before its languages can be changed", v.file.Name())
+	}
+
+	return nil
+}
+
+// Language columns, as named in the header
+func (v *sheetView) languages() []string {
+	header := (*v.layout).values[0]
+	if len(header) <= v.fixed {
+		return []string{}
+	}
+
+	return append([]string(nil), header[v.fixed:]...)
+}
+
+// Every translation file, sheets first
+func (lfs *LanguageFiles) views() ([]*sheetView, error) {
+	views := make([]*sheetView, 0, len(lfs.Sheets)+len(lfs.Dialogues))
+
+	for _, tf := range append(append([]TranslationFile(nil), lfs.Sheets...), lfs.Dialogues...) {
+		v, err := viewSheet(tf)
+		if err != nil {
+			return nil, err
+		}
+		if err = v.check(); err != nil {
+			return nil, err
+		}
+
+		views = append(views, v)
+	}
+
+	return views, nil
+}
+
+// What a language operation changes in memory, kept so it can be put back if the operation fails
+type languageState struct {
+	languages []Language
+	layouts   []**csvLayout
+	saved     []*csvLayout
+	rows      []*[]Translation
+	savedRows [][]Translation
+}
+
+func (lfs *LanguageFiles) saveLanguageState(views []*sheetView) *languageState {
+	state := &languageState{languages: append([]Language(nil), lfs.Languages.Languages...)}
+
+	layouts := []**csvLayout{&lfs.Languages.layout}
+	for _, v := range views {
+		layouts = append(layouts, v.layout)
+		state.rows = append(state.rows, v.rows...)
+	}
+	for _, layout := range layouts {
+		state.layouts = append(state.layouts, layout)
+		state.saved = append(state.saved, (*layout).clone())
+	}
+	for _, row := range state.rows {
+		state.savedRows = append(state.savedRows, append([]Translation(nil), (*row)...))
+	}
+
+	return state
+}
+
+func (lfs *LanguageFiles) restoreLanguageState(state *languageState) {
+	lfs.Languages.Languages = state.languages
+	for i, layout := range state.layouts {
+		*layout = state.saved[i]
+	}
+	for i, row := range state.rows {
+		*row = state.savedRows[i]
+	}
+}
+
+// Runs a language operation on the files in memory and writes them,
+// putting everything in memory back as it was if any of it fails
+func (lfs *LanguageFiles) changeLanguages(views []*sheetView, change func() error) error {
+	state := lfs.saveLanguageState(views)
+
+	err := change()
+	if err == nil {
+		err = lfs.writeAll(views)
+	}
+	if err != nil {
+		lfs.restoreLanguageState(state)
+	}
+
+	return err
+}
+
+// Index of a language in LanguageEnable.csv, or -1
+func (lf *LanguageFile) indexOf(name string) int {
+	for i, language := range lf.Languages {
+		if language.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func indexOf(s []string, value string) int {
+	for i, v := range s {
+		if v == value {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Rows with fewer cells than the header get the missing ones as empty translations,
+// so every language stays in its own column
+func padTranslations(row *[]Translation, languages []string) {
+	for len(*row) < len(languages) {
+		*row = append(*row, Translation{Language: languages[len(*row)]})
+	}
+}
+
+// Writes LanguageEnable.csv and every translation file in one go
+func (lfs *LanguageFiles) writeAll(views []*sheetView) error {
+	if lfs.Languages.File == nil {
+		return noFile(TypeLanguage)
+	}
+	if err := lfs.Languages.Validate(); err != nil {
+		return err
+	}
+
+	layouts := make([]**csvLayout, 0, len(views)+1)
+	writes := make([]pendingWrite, 0, len(views)+1)
+
+	data, err := lfs.Languages.encode()
+	if err != nil {
+		return err
+	}
+	layouts = append(layouts, &lfs.Languages.layout)
+	writes = append(writes, pendingWrite{path: lfs.Languages.File.Name(), data: data})
+
+	for _, v := range views {
+		data, err := v.encode()
+		if err != nil {
+			return err
+		}
+		layouts = append(layouts, v.layout)
+		writes = append(writes, pendingWrite{path: v.file.Name(), data: data})
+	}
+
+	StartSnapshot()
+	if err = writeGameFiles(writes); err != nil {
+		return err
+	}
+
+	// What is in memory now matches the disk
+	for i, write := range writes {
+		_, layout, err := readCSV(write.data)
+		if err != nil {
+			return err
+		}
+		*layouts[i] = layout
+	}
+
+	return nil
+}
+
+// Adds a language at the end of LanguageEnable.csv and of every translation file.
+// If seedFrom names a language, its text is copied into the new column,
+// otherwise the new column is left empty.
+func (lfs *LanguageFiles) AddLanguage(lang Language, seedFrom string) error {
+	if err := lang.Validate(); err != nil {
+		return err
+	}
+	if lfs.Languages.layout == nil {
+		return errors.New("LanguageFile must be parsed before languages can be added")
+	}
+	if lfs.Languages.indexOf(lang.Name) >= 0 {
+		return fmt.Errorf("Language %v already exists", lang.Name)
+	}
+	if seedFrom != "" && lfs.Languages.indexOf(seedFrom) < 0 {
+		return fmt.Errorf("Language %v to seed from does not exist", seedFrom)
+	}
+
+	views, err := lfs.views()
+	if err != nil {
+		return err
+	}
+
+	// Check everything before changing anything
+	for _, v := range views {
+		languages := v.languages()
+		if indexOf(languages, lang.Name) >= 0 {
+			return fmt.Errorf("%v already has a %v column", v.file.Name(), lang.Name)
+		}
+		if seedFrom != "" && indexOf(languages, seedFrom) < 0 {
+			return fmt.Errorf("%v has no %v column to seed from", v.file.Name(), seedFrom)
+		}
+	}
+
+	header := languageHeaderRow(lfs.Languages.layout.values)
+	if header < 0 {
+		return fmt.Errorf("LanguageEnable.csv has no %v row", LabelLang)
+	}
+
+	return lfs.changeLanguages(views, func() error {
+		lfs.Languages.Languages = append(lfs.Languages.Languages, lang)
+		lfs.Languages.layout.insertColumn(len(lfs.Languages.Languages), header, lang.Name)
+
+		for _, v := range views {
+			languages := v.languages()
+			seed := indexOf(languages, seedFrom)
+
+			// The header is always the first row of a translation file
+			(*v.layout).insertColumn(v.fixed+len(languages), 0, lang.Name)
+
+			for _, row := range v.rows {
+				padTranslations(row, languages)
+
+				newTranslation := Translation{Language: lang.Name}
+				if seed >= 0 {
+					newTranslation.String = (*row)[seed].String
+				}
+
+				*row = append(*row, newTranslation)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Checks every translation file has the same languages as LanguageEnable.csv, in any order
+func (lfs *LanguageFiles) checkLanguageSets(views []*sheetView) error {
+	expected := make(map[string]bool)
+	for _, language := range lfs.Languages.Languages {
+		expected[language.Name] = true
+	}
+
+	for _, v := range views {
+		languages := v.languages()
+		found := make(map[string]bool)
+		for _, language := range languages {
+			found[language] = true
+		}
+
+		if len(languages) != len(expected) || len(found) != len(expected) {
+			return fmt.Errorf("%v has languages %v, which don't match %v", v.file.Name(), languages, lfs.Languages.Names())
+		}
+		for language := range expected {
+			if !found[language] {
+				return fmt.Errorf("%v has no %v column", v.file.Name(), language)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Names of every language, in order
+func (lf *LanguageFile) Names() []string {
+	names := make([]string, len(lf.Languages))
+	for i, language := range lf.Languages {
+		names[i] = language.Name
+	}
+
+	return names
+}
+
+// Gets everything ready for an operation on an existing language
+func (lfs *LanguageFiles) prepareLanguageChange(name string) ([]*sheetView, int, error) {
+	if lfs.Languages.layout == nil {
+		return nil, -1, errors.New("LanguageFile must be parsed before languages can be changed")
+	}
+
+	index := lfs.Languages.indexOf(name)
+	if index < 0 {
+		return nil, -1, fmt.Errorf("Language %v does not exist", name)
+	}
+
+	views, err := lfs.views()
+	if err != nil {
+		return nil, -1, err
+	}
+
+	if err = lfs.checkLanguageSets(views); err != nil {
+		return nil, -1, err
+	}
+
+	return views, index, nil
+}
+
+// Removes a language from LanguageEnable.csv and every translation file, along with all its text
+func (lfs *LanguageFiles) RemoveLanguage(name string) error {
+	views, index, err := lfs.prepareLanguageChange(name)
+	if err != nil {
+		return err
+	}
+	if len(lfs.Languages.Languages) == 1 {
+		return errors.New("Refusing to remove the last language")
+	}
+
+	return lfs.changeLanguages(views, func() error {
+		lfs.Languages.Languages = removeElement(lfs.Languages.Languages, index)
+		lfs.Languages.layout.removeColumn(index + 1)
+
+		for _, v := range views {
+			languages := v.languages()
+			column := indexOf(languages, name)
+
+			(*v.layout).removeColumn(v.fixed + column)
+
+			for _, row := range v.rows {
+				padTranslations(row, languages)
+				*row = removeElement(*row, column)
+			}
+		}
+
+		return lfs.checkLanguageSets(views)
+	})
+}
+
+// Changes the Name and NativeName of a language everywhere.
+// The Name is also the column header in every translation file.
+func (lfs *LanguageFiles) RenameLanguage(name, newName, newNativeName string) error {
+	views, index, err := lfs.prepareLanguageChange(name)
+	if err != nil {
+		return err
+	}
+	renamed := lfs.Languages.Languages[index]
+	renamed.Name, renamed.NativeName = newName, newNativeName
+	if err = renamed.Validate(); err != nil {
+		return err
+	}
+	if newName != name && lfs.Languages.indexOf(newName) >= 0 {
+		return fmt.Errorf("Language %v already exists", newName)
+	}
+
+	return lfs.changeLanguages(views, func() error {
+		lfs.Languages.Languages[index].Name = newName
+		lfs.Languages.Languages[index].NativeName = newNativeName
+
+		for _, v := range views {
+			languages := v.languages()
+			column := indexOf(languages, name)
+
+			(*v.layout).setHeader(v.fixed+column, newName)
+
+			for _, row := range v.rows {
+				padTranslations(row, languages)
+				(*row)[column].Language = newName
+			}
+		}
+
+		return lfs.checkLanguageSets(views)
+	})
+}
+
+// Moves a language to another position (starting at 0) in LanguageEnable.csv and every translation file.
+// A file with its languages in another order gets it right after the language it follows in LanguageEnable.csv.
+func (lfs *LanguageFiles) MoveLanguage(name string, position int) error {
+	views, index, err := lfs.prepareLanguageChange(name)
+	if err != nil {
+		return err
+	}
+	if position < 0 || position >= len(lfs.Languages.Languages) {
+		return fmt.Errorf("Position %d is out of range, there are %d languages", position, len(lfs.Languages.Languages))
+	}
+
+	return lfs.changeLanguages(views, func() error {
+		lfs.Languages.Languages = moveElement(lfs.Languages.Languages, index, position)
+		lfs.Languages.layout.moveColumn(index+1, position+1)
+
+		after := ""
+		if position > 0 {
+			after = lfs.Languages.Languages[position-1].Name
+		}
+
+		for _, v := range views {
+			languages := v.languages()
+			column := indexOf(languages, name)
+			target := 0
+			if after != "" {
+				others := removeElement(append([]string(nil), languages...), column)
+				target = indexOf(others, after) + 1
+			}
+
+			(*v.layout).moveColumn(v.fixed+column, v.fixed+target)
+
+			for _, row := range v.rows {
+				padTranslations(row, languages)
+				*row = moveElement(*row, column, target)
+			}
+		}
+
+		return lfs.checkLanguageSets(views)
+	})
+}
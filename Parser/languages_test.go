@@ -0,0 +1,393 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Checks every translation file has exactly these language columns
+func checkLanguages(t *testing.T, gameFiles LanguageFiles, expected []string) {
+	t.Helper()
+
+	views, err := gameFiles.views()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range views {
+		languages := v.languages()
+		if len(languages) != len(expected) {
+			t.Errorf("%v has languages %v, expected %v", v.file.Name(), languages, expected)
+			continue
+		}
+		for i := range expected {
+			if languages[i] != expected[i] {
+				t.Errorf("%v has languages %v, expected %v", v.file.Name(), languages, expected)
+				break
+			}
+		}
+
+		for _, row := range v.rows {
+			if len(*row) != len(expected) {
+				t.Errorf("%v has a row with %d translations, expected %d", v.file.Name(), len(*row), len(expected))
+				break
+			}
+		}
+	}
+
+	if len(gameFiles.Languages.Languages) != len(expected) {
+		t.Errorf("LanguageEnable.csv has %d languages, expected %d", len(gameFiles.Languages.Languages), len(expected))
+	}
+}
+
+func TestAddLanguage(t *testing.T) {
+	t.Log("Testing AddLanguage...")
+
+	gamePath, gameFiles := openTestGame(t)
+
+	german := Language{
+		Name:                   "German",
+		NativeName:             "Deutsch",
+		Enabled:                true,
+		FontSize:               55,
+		OffsetAmount:           3,
+		CharacterWidth:         40,
+		CharacterWidthFancy:    56,
+		CharacterWidthDialogue: 40,
+	}
+	if err := gameFiles.AddLanguage(german, "English"); err != nil {
+		t.Fatalf("Failed to add language with error:\n%v", err)
+	}
+	t.Log("Language added...")
+
+	// Everything should be on disk now
+	gameFiles = parseTestGame(t, gamePath)
+	checkLanguages(t, gameFiles, []string{"English", "Japanese", "Chinese", "German"})
+
+	added := gameFiles.Languages.Languages[3]
+	if added.NativeName != "Deutsch" || !added.Enabled || added.FontSize != 55 || added.CharacterWidthFancy != 56 {
+		t.Errorf("Language was not written correctly: %+v", added)
+	}
+
+	names := gameFiles.Sheets[0].(*NameSheet)
+	if names.Strings[2].Strings[3].String != names.Strings[2].Strings[0].String {
+		t.Errorf("German was not seeded from English: %+v", names.Strings[2])
+	}
+
+	data, err := os.ReadFile(names.File.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte("\r\n,,,,,\r\n")) {
+		t.Errorf("Spacer row did not grow with the new column:\n%q", data)
+	}
+
+	t.Log("AddLanguage Passed!")
+}
+
+func TestAddLanguageRejected(t *testing.T) {
+	t.Log("Testing AddLanguage with bad input...")
+
+	gamePath := newTestGame(t)
+	before, err := os.ReadFile(gamePath + "/Data/LanguageEnable.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gameFiles := parseTestGame(t, gamePath)
+
+	if err = gameFiles.AddLanguage(Language{Name: "Japanese"}, ""); err == nil {
+		t.Errorf("Adding an existing language should fail")
+	}
+	if err = gameFiles.AddLanguage(Language{Name: "German"}, "Klingon"); err == nil {
+		t.Errorf("Seeding from a language that doesn't exist should fail")
+	}
+
+	// Something went wrong with the layout, there is no header to add the language to
+	gameFiles.Languages.layout.values[0][0] = "Language"
+	if err = gameFiles.AddLanguage(Language{Name: "German"}, ""); err == nil {
+		t.Errorf("Adding a language without a Lang row should fail")
+	}
+	gameFiles.Languages.layout.values[0][0] = LabelLang
+
+	after, err := os.ReadFile(gamePath + "/Data/LanguageEnable.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Errorf("A rejected language should not touch the files")
+	}
+	checkLanguages(t, gameFiles, []string{"English", "Japanese", "Chinese"})
+
+	t.Log("AddLanguage with bad input Passed!")
+}
+
+func TestAddLanguageLangRowNotFirst(t *testing.T) {
+	t.Log("Testing AddLanguage with the Lang row further down...")
+
+	gamePath := newTestGame(t)
+	writeTestFile(t, gamePath+"/Data/LanguageEnable.csv", "Desc,English,日本語,中文\r\nLang,English,Japanese,Chinese\r\nenabled,1,1,0\r\n")
+
+	gameFiles := parseTestGame(t, gamePath)
+	german := NewLanguage("German")
+	german.NativeName = "Deutsch"
+	if err := gameFiles.AddLanguage(german, ""); err != nil {
+		t.Fatalf("Failed to add a language with error:\n%v", err)
+	}
+
+	data, err := os.ReadFile(gamePath + "/Data/LanguageEnable.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "Desc,English,日本語,中文,Deutsch\r\nLang,English,Japanese,Chinese,German\r\n") {
+		t.Errorf("Unexpected LanguageEnable.csv:\n%s", data)
+	}
+
+	t.Log("AddLanguage with the Lang row further down Passed!")
+}
+
+func TestRemoveRenameMoveLanguage(t *testing.T) {
+	t.Log("Testing RemoveLanguage, RenameLanguage and MoveLanguage...")
+
+	gamePath, gameFiles := openTestGame(t)
+
+	if err := gameFiles.RenameLanguage("Chinese", "SChinese", "简体中文"); err != nil {
+		t.Fatalf("Failed to rename language with error:\n%v", err)
+	}
+	if err := gameFiles.MoveLanguage("SChinese", 0); err != nil {
+		t.Fatalf("Failed to move language with error:\n%v", err)
+	}
+	if err := gameFiles.RemoveLanguage("Japanese"); err != nil {
+		t.Fatalf("Failed to remove language with error:\n%v", err)
+	}
+	checkLanguages(t, gameFiles, []string{"SChinese", "English"})
+
+	// Everything should be on disk now
+	gameFiles = parseTestGame(t, gamePath)
+	checkLanguages(t, gameFiles, []string{"SChinese", "English"})
+
+	languages := gameFiles.Languages.Languages
+	if languages[0].NativeName != "简体中文" || languages[0].FontName != "NotoSansSC" || languages[1].FontSize != 55 {
+		t.Errorf("Languages were not written correctly: %+v", languages)
+	}
+
+	dialogue := gameFiles.Dialogues[0].(*DialogueFile)
+	if dialogue.Strings[0].Translations[0].String != "你好。" || dialogue.Strings[0].Translations[1].Language != "English" {
+		t.Errorf("Dialogue columns were not moved correctly: %+v", dialogue.Strings[0])
+	}
+
+	if err := gameFiles.RemoveLanguage("Japanese"); err == nil {
+		t.Errorf("Removing a language that doesn't exist should fail")
+	}
+	if err := gameFiles.MoveLanguage("English", 5); err == nil {
+		t.Errorf("Moving a language out of range should fail")
+	}
+	if err := gameFiles.RenameLanguage("English", "SChinese", ""); err == nil {
+		t.Errorf("Renaming a language to an existing one should fail")
+	}
+
+	t.Log("RemoveLanguage, RenameLanguage and MoveLanguage Passed!")
+}
+
+func TestMoveLanguageOwnOrder(t *testing.T) {
+	t.Log("Testing MoveLanguage on a sheet with its own order...")
+
+	gamePath := newTestGame(t)
+	writeTestFile(t, gamePath+"/Data/Names_Item.csv", "Key,Level,English,Chinese,Japanese\r\nItem_a,1,A,诶,エー\r\n")
+
+	gameFiles := parseTestGame(t, gamePath)
+
+	// English goes after Chinese, wherever Chinese is
+	if err := gameFiles.MoveLanguage("English", 2); err != nil {
+		t.Fatalf("Failed to move language with error:\n%v", err)
+	}
+	if names := gameFiles.Languages.Names(); strings.Join(names, ",") != "Japanese,Chinese,English" {
+		t.Errorf("Expected English to be last in LanguageEnable.csv, got %v", names)
+	}
+
+	data, err := os.ReadFile(gamePath + "/Data/Names_Item.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "Key,Level,Chinese,English,Japanese\r\nItem_a,1,诶,A,エー\r\n" {
+		t.Errorf("Expected English to be moved right after Chinese:\n%s", data)
+	}
+
+	t.Log("MoveLanguage on a sheet with its own order Passed!")
+}
+
+func TestLanguageChangeRollback(t *testing.T) {
+	t.Log("Testing failed language changes...")
+
+	_, gameFiles := openTestGame(t)
+
+	// Writing fails once it gets to the dialogue
+	dialogue := gameFiles.Dialogues[0].(*DialogueFile)
+	before := dialogue.Strings[0].Translations[1]
+	if err := os.Remove(dialogue.File.Name()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gameFiles.RemoveLanguage("Japanese"); err == nil {
+		t.Errorf("Removing a language should fail when a file can't be written")
+	}
+	if err := gameFiles.RenameLanguage("Chinese", "SChinese", "简体中文"); err == nil {
+		t.Errorf("Renaming a language should fail when a file can't be written")
+	}
+	if err := gameFiles.MoveLanguage("Chinese", 0); err == nil {
+		t.Errorf("Moving a language should fail when a file can't be written")
+	}
+	if err := gameFiles.AddLanguage(Language{Name: "German", NativeName: "Deutsch"}, "English"); err == nil {
+		t.Errorf("Adding a language should fail when a file can't be written")
+	}
+
+	checkLanguages(t, gameFiles, []string{"English", "Japanese", "Chinese"})
+	if dialogue.Strings[0].Translations[1] != before {
+		t.Errorf("Expected the dialogue to be put back, got %+v", dialogue.Strings[0].Translations)
+	}
+
+	t.Log("Failed language changes Passed!")
+}
+
+func TestLanguageFileUpdate(t *testing.T) {
+	t.Log("Testing LanguageFile update...")
+
+	gamePath := newTestGame(t)
+	path := gamePath + "/Data/LanguageEnable.csv"
+	original, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	languageFile := &LanguageFile{File: file}
+	testRoundTrip(t, file, languageFile)
+	t.Log("Round trip done...")
+
+	languageFile.Languages[1].FontSize = 60
+	languageFile.Languages[2].Enabled = true
+	if err = languageFile.Update(); err != nil {
+		t.Fatalf("Failed to update with error:\n%v", err)
+	}
+
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := bytes.Replace(original, []byte("fontSize,55,48,48"), []byte("fontSize,55,60,48"), 1)
+	expected = bytes.Replace(expected, []byte("enabled,1,1,0"), []byte("enabled,1,1,1"), 1)
+	if !bytes.Equal(written, expected) {
+		t.Errorf("Update wrote:\n%s\nExpected:\n%s", written, expected)
+	}
+
+	// Plenty of installs leave Desc empty, that shouldn't block anything
+	unnamed := languageFile.Languages[0]
+	unnamed.NativeName = ""
+	if err = unnamed.Validate(); err != nil {
+		t.Errorf("An empty Desc should be fine, got %v", err)
+	}
+
+	// None of these should reach the disk
+	languageFile.Languages[0].FontSize = -1
+	languageFile.Languages[1].FontName = ""
+	err = languageFile.Update()
+	if err == nil {
+		t.Fatalf("Invalid languages should not be written")
+	}
+
+	var fieldErr *LanguageFieldError
+	if !errors.As(err, &fieldErr) {
+		t.Errorf("Expected a LanguageFieldError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "fontSize") || !strings.Contains(err.Error(), "font:") {
+		t.Errorf("Expected both invalid fields to be reported, got %v", err)
+	}
+
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(after, written) {
+		t.Errorf("Invalid languages were written anyway")
+	}
+
+	t.Log("LanguageFile update Passed!")
+}
+
+func TestLanguageFileLabels(t *testing.T) {
+	t.Log("Testing LanguageFile with shuffled, unknown and missing rows...")
+
+	path := t.TempDir() + "/LanguageEnable.csv"
+	data := "Desc,English,日本語\r\n" +
+		"Lang,English,Japanese\r\n" +
+		"lineSpacing,4,6\r\n" +
+		"enabled,1,0\r\n" +
+		"characterWidth,40\r\n"
+	writeTestFile(t, path, data)
+	useTempBackups(t)
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	languageFile := &LanguageFile{File: file}
+	testRoundTrip(t, file, languageFile)
+
+	japanese := languageFile.Languages[1]
+	if japanese.Name != "Japanese" || japanese.NativeName != "日本語" || japanese.Enabled {
+		t.Errorf("Rows were not matched by label: %+v", japanese)
+	}
+	if japanese.FontSize != 55 || japanese.CharacterWidth != 40 || japanese.CharacterWidthFancy != 56 {
+		t.Errorf("Missing rows should leave the defaults: %+v", japanese)
+	}
+	if japanese.Extra["lineSpacing"] != "6" {
+		t.Errorf("Unknown rows should be kept, got %v", japanese.Extra)
+	}
+
+	languageFile.Languages[1].FontSize = 48
+	languageFile.Languages[1].Extra["lineSpacing"] = "7"
+	if err = languageFile.Update(); err != nil {
+		t.Fatalf("Failed to update with error:\n%v", err)
+	}
+
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Desc,English,日本語\r\n" +
+		"Lang,English,Japanese\r\n" +
+		"lineSpacing,4,7\r\n" +
+		"enabled,1,0\r\n" +
+		"characterWidth,40\r\n" +
+		"fontSize,55,48\r\n"
+	if string(written) != expected {
+		t.Errorf("Update wrote:\n%q\nExpected:\n%q", written, expected)
+	}
+
+	t.Log("LanguageFile with shuffled, unknown and missing rows Passed!")
+}
+
+func TestLanguageFileWithoutLangRow(t *testing.T) {
+	path := t.TempDir() + "/LanguageEnable.csv"
+	writeTestFile(t, path, "Desc,English\r\n")
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if err = (&LanguageFile{File: file}).Parse(); err == nil {
+		t.Errorf("A LanguageFile without a Lang row should fail to parse")
+	}
+}
@@ -0,0 +1,222 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// What a Token is
+type TokenKind uint8
+
+const (
+	// Something the game fills in, like {0}, {name} or %s
+	TokenFormat TokenKind = iota
+
+	// A tag, like <color=red>, </color> or [b]
+	TokenMarkup
+
+	// A line break, either a real one or a written \n
+	TokenLineBreak
+)
+
+func (k TokenKind) String() string {
+	switch k {
+	case TokenFormat:
+		return "format"
+	case TokenMarkup:
+		return "markup"
+	case TokenLineBreak:
+		return "line break"
+	}
+
+	return fmt.Sprintf("TokenKind(%d)", uint8(k))
+}
+
+// Something in a string that has to survive translation
+type Token struct {
+	Kind TokenKind
+	Text string
+
+	// Byte offset in the string
+	Offset int
+}
+
+// Codes of the issues found by LintPlaceholders
+const (
+	CodeTokenMissing = "token-missing"
+	CodeTokenExtra   = "token-extra"
+	CodeTokenOrder   = "token-order"
+	CodeLineBreaks   = "line-breaks"
+)
+
+// Order matters: %% has to be matched before it can be taken for the start of a printf verb.
+// The space flag is left out on purpose, or prose like "50% of" would read as a % o verb.
+var tokenPattern = regexp.MustCompile(
+	`%%` +
+		`|\{[A-Za-z0-9_.:,#\-]*\}` +
+		`|%(?:\d+\$)?[-+#0]*\d*(?:\.\d+)?[sdifuxXcgeEo]` +
+		`|</?[A-Za-z][A-Za-z0-9_\-]*(?:[= ][^<>]*)?>` +
+		`|\[/?[A-Za-z][A-Za-z0-9_\-]*(?:=[^\[\]]*)?\]` +
+		`|\r\n|\n|\\n`)
+
+// Tokens that name what they stand for, which translations are free to move around
+var positionalPattern = regexp.MustCompile(`^(?:\{\d+(?::[^}]*)?\}|%\d+\$)`)
+
+// Every format token, tag and line break of a string, in order
+func Tokens(s string) []Token {
+	tokens := make([]Token, 0)
+
+	for _, match := range tokenPattern.FindAllStringIndex(s, -1) {
+		text := s[match[0]:match[1]]
+
+		kind := TokenFormat
+		switch {
+		case text == "%%":
+			// An escaped percent sign is just text
+			continue
+		case text == "\n" || text == "\r\n" || text == `\n`:
+			kind = TokenLineBreak
+		case text[0] == '<' || text[0] == '[':
+			kind = TokenMarkup
+		}
+
+		tokens = append(tokens, Token{kind, text, match[0]})
+	}
+
+	return tokens
+}
+
+// Compares the tokens of every translation with the ones of the reference language, dialogue included.
+// Empty translations are skipped, they just haven't been translated yet.
+// If reference is empty, the first language of LanguageEnable.csv is used.
+func LintPlaceholders(lfs LanguageFiles, reference string) ([]Issue, error) {
+	if reference == "" {
+		if len(lfs.Languages.Languages) == 0 {
+			return nil, errors.New("There are no languages to use as a reference")
+		}
+		reference = lfs.Languages.Languages[0].Name
+	}
+	if lfs.Languages.indexOf(reference) < 0 {
+		return nil, fmt.Errorf("Language %v does not exist", reference)
+	}
+
+	issues := make([]Issue, 0)
+
+	for _, tf := range append(append([]TranslationFile(nil), lfs.Sheets...), lfs.Dialogues...) {
+		v, err := viewSheet(tf)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range FileEntries(tf) {
+			source, ok := entry.Get(reference)
+			if !ok || source == "" {
+				continue
+			}
+			sourceTokens := Tokens(source)
+
+			for column, lang := range entry.Languages {
+				target, _ := entry.Get(lang)
+				if lang == reference || target == "" {
+					continue
+				}
+
+				for _, problem := range compareTokens(sourceTokens, Tokens(target)) {
+					issue := cellIssue(v, &entry, column)
+					issue.Severity, issue.Code, issue.Message = problem.severity, problem.code, problem.message
+					issues = append(issues, issue)
+				}
+			}
+		}
+	}
+
+	return issues, nil
+}
+
+type tokenProblem struct {
+	severity Severity
+	code     string
+	message  string
+}
+
+func compareTokens(source, target []Token) []tokenProblem {
+	problems := make([]tokenProblem, 0)
+
+	counts := make(map[string]int)
+	sourceBreaks, targetBreaks := 0, 0
+	for _, token := range source {
+		if token.Kind == TokenLineBreak {
+			sourceBreaks++
+			continue
+		}
+		counts[token.Text]++
+	}
+
+	extra := make([]string, 0)
+	for _, token := range target {
+		if token.Kind == TokenLineBreak {
+			targetBreaks++
+			continue
+		}
+		if counts[token.Text] > 0 {
+			counts[token.Text]--
+		} else {
+			extra = append(extra, token.Text)
+		}
+	}
+
+	missing := make([]string, 0)
+	for _, token := range source {
+		if token.Kind != TokenLineBreak && counts[token.Text] > 0 {
+			counts[token.Text]--
+			missing = append(missing, token.Text)
+		}
+	}
+
+	if len(missing) > 0 {
+		problems = append(problems, tokenProblem{SeverityError, CodeTokenMissing, "Missing " + quoteTokens(missing)})
+	}
+	if len(extra) > 0 {
+		problems = append(problems, tokenProblem{SeverityError, CodeTokenExtra, "Unexpected " + quoteTokens(extra)})
+	}
+
+	// Only worth checking once the same tokens are there,
+	// and only for the ones that don't say which value they stand for
+	if len(missing) == 0 && len(extra) == 0 {
+		sourceOrder, targetOrder := orderedTokens(source), orderedTokens(target)
+		if strings.Join(sourceOrder, "\x00") != strings.Join(targetOrder, "\x00") {
+			problems = append(problems, tokenProblem{SeverityWarning, CodeTokenOrder, fmt.Sprintf("Expected %v in that order, got %v", quoteTokens(sourceOrder), quoteTokens(targetOrder))})
+		}
+	}
+
+	// Translations often need to break lines somewhere else, but rarely a different amount of times
+	if sourceBreaks != targetBreaks {
+		problems = append(problems, tokenProblem{SeverityInfo, CodeLineBreaks, fmt.Sprintf("Expected %d line breaks, got %d", sourceBreaks, targetBreaks)})
+	}
+
+	return problems
+}
+
+// Tokens whose order matters
+func orderedTokens(tokens []Token) []string {
+	texts := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if token.Kind == TokenLineBreak || positionalPattern.MatchString(token.Text) {
+			continue
+		}
+		texts = append(texts, token.Text)
+	}
+
+	return texts
+}
+
+func quoteTokens(tokens []string) string {
+	quoted := make([]string, len(tokens))
+	for i, token := range tokens {
+		quoted[i] = fmt.Sprintf("%q", token)
+	}
+
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,83 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokens(t *testing.T) {
+	t.Log("Testing Tokens...")
+
+	tokens := Tokens("<color=red>{0}</color> costs %d%% of [b]{name}[/b]\\nDone\r\n[Press A]")
+	texts := make([]string, len(tokens))
+	for i, token := range tokens {
+		texts[i] = token.Text
+	}
+
+	expected := []string{"<color=red>", "{0}", "</color>", "%d", "[b]", "{name}", "[/b]", `\n`, "\r\n"}
+	if !reflect.DeepEqual(texts, expected) {
+		t.Errorf("Expected %q, got %q", expected, texts)
+	}
+	if tokens[0].Kind != TokenMarkup || tokens[1].Kind != TokenFormat || tokens[7].Kind != TokenLineBreak || tokens[1].Offset != 11 {
+		t.Errorf("Tokens have the wrong kinds or offsets: %+v", tokens)
+	}
+
+	// Percent signs in prose aren't printf verbs
+	for _, prose := range []string{"Deals 50% of damage", "I'm 100% sure", "Heals 10% extra"} {
+		if tokens := Tokens(prose); len(tokens) != 0 {
+			t.Errorf("Expected no tokens in %q, got %+v", prose, tokens)
+		}
+	}
+	if tokens := Tokens("%-5d and %+.2f"); len(tokens) != 2 {
+		t.Errorf("Expected flagged verbs to still be tokens, got %+v", tokens)
+	}
+
+	t.Log("Tokens Passed!")
+}
+
+func TestLintPlaceholders(t *testing.T) {
+	t.Log("Testing LintPlaceholders...")
+
+	gamePath := newTestGame(t)
+	writeTestFile(t, gamePath+"/Data/Strings_Menu.csv", "Key,English,Japanese,Chinese\r\n"+
+		"ok,<b>{0}</b> of {1},{1}の<b>{0}</b>,{1}的<b>{0}</b>\r\n"+
+		"missing,Hit %s for %d,%sで,\r\n"+
+		"order,<i>%s</i> and %d,%d と <i>%s</i>,<i>%s</i>和%d\r\n"+
+		"breaks,Two\\nlines,二行,两\\n行\r\n")
+	writeTestFile(t, gamePath+"/Dialog/frog.csv", "type,flag,expression,English,Japanese,Chinese\r\n"+
+		"0,1,1,Hi [name]!,やあ!,[name][name]你好!\r\n")
+
+	gameFiles := parseTestGame(t, gamePath)
+
+	if _, err := LintPlaceholders(gameFiles, "Klingon"); err == nil {
+		t.Error("Linted against a language that doesn't exist")
+	}
+
+	issues, err := LintPlaceholders(gameFiles, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type found struct {
+		sheet, key, language, code string
+		column                     int
+	}
+	got := make([]found, len(issues))
+	for i, issue := range issues {
+		t.Log(issue)
+		got[i] = found{issue.Sheet, issue.Key, issue.Language, issue.Code, issue.Column}
+	}
+
+	expected := []found{
+		{"Data/Strings_Menu.csv", "missing", "Japanese", CodeTokenMissing, 3},
+		{"Data/Strings_Menu.csv", "order", "Japanese", CodeTokenOrder, 3},
+		{"Data/Strings_Menu.csv", "breaks", "Japanese", CodeLineBreaks, 3},
+		{"Dialog/frog.csv", "2", "Japanese", CodeTokenMissing, 5},
+		{"Dialog/frog.csv", "2", "Chinese", CodeTokenExtra, 6},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+
+	t.Log("LintPlaceholders Passed!")
+}
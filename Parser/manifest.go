@@ -0,0 +1,232 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Name of the file where the game lists every sheet it loads, inside the Data folder
+const SheetListName string = "SheetList.csv"
+
+// A translation sheet, as found in SheetList.csv or in the Data folder
+type SheetEntry struct {
+	// Name of the sheet, without the extension (e.g. Names_HBS)
+	Name string
+	Type FileType
+	Path string
+}
+
+// The translation sheets of a game install
+type SheetManifest struct {
+	// Listed sheets that exist, in the order they are listed
+	Sheets []SheetEntry
+
+	// Listed sheets that are not in the Data folder
+	Missing []SheetEntry
+
+	// Sheets in the Data folder that are not listed
+	Unlisted []SheetEntry
+
+	// Whether SheetList.csv wasn't there and the Known*Files were used instead
+	FromKnownFiles bool
+}
+
+// Works out the FileType of a sheet from its name.
+// Sheets that aren't translation sheets (stats, the language file, the list itself...) return false.
+func SheetFileType(name string) (FileType, bool) {
+	name = trimCSVExt(name)
+
+	for _, enum := range KnownStringEnumFiles {
+		if name == "Strings_"+enum {
+			return TypeStringEnum, true
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(name, "Names_"):
+		return TypeName, true
+	case strings.HasPrefix(name, "Descriptions_"):
+		return TypeDescription, true
+	case strings.HasPrefix(name, "Titles_"):
+		return TypeTitle, true
+	case name == "Strings", strings.HasPrefix(name, "Strings_"):
+		return TypeString, true
+	}
+
+	return 0, false
+}
+
+// Reads SheetList.csv and checks it against the Data folder.
+//
+// We don't rely on the exact layout of the list,
+// every field naming a translation sheet counts, in the order they appear.
+// If the list doesn't exist, the Known*Files are used instead.
+func LoadSheetManifest(gamePath string) (SheetManifest, error) {
+	var manifest SheetManifest
+	dataPath := gamePath + "/Data"
+
+	names, err := readSheetList(dataPath + "/" + SheetListName)
+	if errors.Is(err, fs.ErrNotExist) {
+		names = knownSheetNames()
+		manifest.FromKnownFiles = true
+	} else if err != nil {
+		return manifest, err
+	}
+
+	onDisk, err := findSheetFiles(dataPath)
+	if err != nil {
+		return manifest, err
+	}
+
+	listed := make(map[string]bool)
+	for _, name := range names {
+		fileType, ok := SheetFileType(name)
+		if !ok || listed[name] {
+			continue
+		}
+		listed[name] = true
+
+		entry := SheetEntry{
+			Name: name,
+			Type: fileType,
+			Path: dataPath + "/" + name + ".csv",
+		}
+
+		if onDisk[name] {
+			manifest.Sheets = append(manifest.Sheets, entry)
+		} else {
+			manifest.Missing = append(manifest.Missing, entry)
+		}
+	}
+
+	for _, name := range sortedKeys(onDisk) {
+		if listed[name] {
+			continue
+		}
+
+		fileType, _ := SheetFileType(name)
+		manifest.Unlisted = append(manifest.Unlisted, SheetEntry{
+			Name: name,
+			Type: fileType,
+			Path: dataPath + "/" + name + ".csv",
+		})
+	}
+
+	return manifest, nil
+}
+
+// Everything that doesn't match the Data folder, as warnings
+func (sm *SheetManifest) Warnings() []error {
+	warnings := make([]error, 0)
+
+	if sm.FromKnownFiles {
+		warnings = append(warnings, errors.New(SheetListName+" was not found, using the known sheets instead"))
+	}
+	for _, entry := range sm.Missing {
+		warnings = append(warnings, fmt.Errorf("Sheet %v is listed in %v but does not exist", entry.Name, SheetListName))
+	}
+	for _, entry := range sm.Unlisted {
+		warnings = append(warnings, fmt.Errorf("Sheet %v exists but is not listed in %v", entry.Name, SheetListName))
+	}
+
+	return warnings
+}
+
+// Every non-empty field of SheetList.csv, extensions removed
+func readSheetList(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	records, err := parseFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0)
+	for _, record := range records {
+		for _, field := range record {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+
+			names = append(names, trimCSVExt(field))
+		}
+	}
+
+	return names, nil
+}
+
+// The sheets we knew about before reading SheetList.csv, in the order they used to be loaded
+func knownSheetNames() []string {
+	names := make([]string, 0)
+
+	for _, name := range KnownNameFiles {
+		names = append(names, "Names_"+name)
+	}
+	for _, name := range KnownDescriptionFiles {
+		names = append(names, "Descriptions_"+name)
+	}
+	for _, name := range KnownTitleFiles {
+		names = append(names, "Titles_"+name)
+	}
+
+	// Don't forget the one without any extra '_x'
+	names = append(names, "Strings")
+	for _, name := range KnownStringFiles {
+		names = append(names, "Strings_"+name)
+	}
+	for _, name := range KnownStringEnumFiles {
+		names = append(names, "Strings_"+name)
+	}
+
+	return names
+}
+
+// Translation sheets present in the Data folder
+func findSheetFiles(dataPath string) (map[string]bool, error) {
+	entries, err := os.ReadDir(dataPath)
+	if err != nil {
+		return nil, err
+	}
+
+	sheets := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".csv") {
+			continue
+		}
+
+		name := trimCSVExt(entry.Name())
+		if _, ok := SheetFileType(name); ok {
+			sheets[name] = true
+		}
+	}
+
+	return sheets, nil
+}
+
+func trimCSVExt(name string) string {
+	if strings.EqualFold(filepath.Ext(name), ".csv") {
+		return strings.TrimSuffix(name, filepath.Ext(name))
+	}
+
+	return name
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,181 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// Where a string is shown, which decides the character width the game uses for it
+type WidthContext uint8
+
+const (
+	// Uses characterWidth
+	ContextNormal WidthContext = iota
+
+	// Uses characterWidthFancy
+	ContextFancy
+
+	// Uses characterWidthDialog
+	ContextDialogue
+)
+
+func (c WidthContext) String() string {
+	switch c {
+	case ContextNormal:
+		return "normal"
+	case ContextFancy:
+		return "fancy"
+	case ContextDialogue:
+		return "dialogue"
+	}
+
+	return fmt.Sprintf("WidthContext(%d)", uint8(c))
+}
+
+// Width the game gives each character of the language in a context
+func (l *Language) characterWidth(context WidthContext) int {
+	switch context {
+	case ContextFancy:
+		return l.CharacterWidthFancy
+	case ContextDialogue:
+		return l.CharacterWidthDialogue
+	}
+
+	return l.CharacterWidth
+}
+
+// Estimates how wide a string is once the game renders it, in the same units as the character widths.
+//
+// Every character gets the language's character width, the way the game does it.
+// For full width languages that width is the one of a full width character, so narrow ones (like latin letters) get half of it;
+// for the rest it is the one of a narrow character, and full width ones get twice as much.
+// Tags aren't rendered, and with several lines, the widest one counts.
+func (l *Language) TextWidth(s string, context WidthContext) float64 {
+	condition := &runewidth.Condition{EastAsianWidth: l.FullWidth, StrictEmojiNeutral: true}
+	unit := float64(l.characterWidth(context))
+	if l.FullWidth {
+		unit /= 2
+	}
+
+	widest, line := 0, 0
+	offset := 0
+	for _, token := range Tokens(s) {
+		line += condition.StringWidth(s[offset:token.Offset])
+		offset = token.Offset + len(token.Text)
+
+		switch token.Kind {
+		case TokenFormat:
+			// We can't know what goes there, so count it as written
+			line += condition.StringWidth(token.Text)
+		case TokenLineBreak:
+			widest = max(widest, line)
+			line = 0
+		}
+	}
+	line += condition.StringWidth(s[offset:])
+
+	return float64(max(widest, line)) * unit
+}
+
+// Codes of the issues found by CheckOverflow
+const (
+	CodeOverflowRatio = "overflow-ratio"
+	CodeOverflowLimit = "overflow-limit"
+)
+
+// What CheckOverflow looks for
+type OverflowOptions struct {
+	// Language the others are compared to.
+	// If empty, the first language of LanguageEnable.csv is used.
+	Reference string
+
+	// Flag translations wider than the reference times this, e.g. 1.2 for 20% wider.
+	// 0 turns the check off.
+	Ratio float64
+
+	// Flag translations wider than this, whatever the reference is.
+	// 0 turns the check off.
+	Limit float64
+
+	// Works out where each entry is shown.
+	// If nil, dialogue uses ContextDialogue, titles ContextFancy and everything else ContextNormal.
+	Context func(entry *Entry) WidthContext
+}
+
+func defaultWidthContext(entry *Entry) WidthContext {
+	switch entry.Type {
+	case TypeDialogue:
+		return ContextDialogue
+	case TypeTitle:
+		return ContextFancy
+	}
+
+	return ContextNormal
+}
+
+// Flags translations that are likely to overflow their box, dialogue included.
+// Widths are compared after every language's character widths are applied, so they are an estimate, not a measurement.
+func CheckOverflow(lfs LanguageFiles, opts OverflowOptions) ([]Issue, error) {
+	if opts.Ratio <= 0 && opts.Limit <= 0 {
+		return nil, errors.New("Neither a ratio nor a limit was given")
+	}
+	if opts.Context == nil {
+		opts.Context = defaultWidthContext
+	}
+
+	reference := opts.Reference
+	if reference == "" {
+		if len(lfs.Languages.Languages) == 0 {
+			return nil, errors.New("There are no languages to use as a reference")
+		}
+		reference = lfs.Languages.Languages[0].Name
+	}
+	referenceIndex := lfs.Languages.indexOf(reference)
+	if referenceIndex < 0 {
+		return nil, fmt.Errorf("Language %v does not exist", reference)
+	}
+	referenceLanguage := &lfs.Languages.Languages[referenceIndex]
+
+	issues := make([]Issue, 0)
+
+	for _, tf := range append(append([]TranslationFile(nil), lfs.Sheets...), lfs.Dialogues...) {
+		v, err := viewSheet(tf)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range FileEntries(tf) {
+			context := opts.Context(&entry)
+			source, _ := entry.Get(reference)
+			sourceWidth := referenceLanguage.TextWidth(source, context)
+
+			for column, lang := range entry.Languages {
+				index := lfs.Languages.indexOf(lang)
+				target, _ := entry.Get(lang)
+				if index < 0 || target == "" {
+					continue
+				}
+				width := lfs.Languages.Languages[index].TextWidth(target, context)
+
+				issue := cellIssue(v, &entry, column)
+				issue.Severity = SeverityWarning
+
+				if opts.Limit > 0 && width > opts.Limit {
+					issue.Code = CodeOverflowLimit
+					issue.Message = fmt.Sprintf("%v text is %g wide, over the limit of %g", context, width, opts.Limit)
+					issues = append(issues, issue)
+				}
+				// A source that takes no room, like one that is only markup, has nothing to compare against
+				if opts.Ratio > 0 && lang != reference && source != "" && sourceWidth > 0 && width > sourceWidth*opts.Ratio {
+					issue.Code = CodeOverflowRatio
+					issue.Message = fmt.Sprintf("%v text is %g wide, %.0f%% of the %g of %v", context, width, width/sourceWidth*100, sourceWidth, reference)
+					issues = append(issues, issue)
+				}
+			}
+		}
+	}
+
+	return issues, nil
+}
@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"testing"
+)
+
+func TestTextWidth(t *testing.T) {
+	t.Log("Testing TextWidth...")
+
+	english := NewLanguage("English")
+	japanese := NewLanguage("Japanese")
+	japanese.FullWidth = true
+	japanese.CharacterWidth = 55
+
+	tests := []struct {
+		language *Language
+		text     string
+		context  WidthContext
+		expected float64
+	}{
+		{&english, "Hello", ContextNormal, 200},
+		{&english, "日本", ContextNormal, 160},
+		{&english, "ab", ContextFancy, 112},
+		{&japanese, "日本", ContextNormal, 110},
+		{&japanese, "ab", ContextNormal, 55},
+		{&japanese, "<b>ab</b>\\nabcd", ContextNormal, 110},
+		{&english, "{0} gold", ContextDialogue, 320},
+	}
+	for _, test := range tests {
+		if width := test.language.TextWidth(test.text, test.context); width != test.expected {
+			t.Errorf("Expected %q to be %g wide in %v for %v, got %g", test.text, test.expected, test.context, test.language.Name, width)
+		}
+	}
+
+	t.Log("TextWidth Passed!")
+}
+
+func TestCheckOverflow(t *testing.T) {
+	t.Log("Testing CheckOverflow...")
+
+	gamePath := newTestGame(t)
+	writeTestFile(t, gamePath+"/Data/Strings_Menu.csv", "Key,English,Japanese,Chinese\r\n"+
+		"short,Potion,薬,药水\r\n"+
+		"long,Potion,ポーションをたくさん,药水\r\n"+
+		"icon,<icon=potion>,薬,药水\r\n")
+
+	gameFiles := parseTestGame(t, gamePath)
+
+	if _, err := CheckOverflow(gameFiles, OverflowOptions{}); err == nil {
+		t.Error("Checked without a ratio or a limit")
+	}
+
+	menu := func(issues []Issue) []Issue {
+		found := make([]Issue, 0)
+		for _, issue := range issues {
+			if issue.Sheet == "Data/Strings_Menu.csv" {
+				t.Log(issue)
+				found = append(found, issue)
+			}
+		}
+		return found
+	}
+
+	issues, err := CheckOverflow(gameFiles, OverflowOptions{Ratio: 1.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := menu(issues)
+	if len(found) != 1 || found[0].Key != "long" || found[0].Language != "Japanese" || found[0].Code != CodeOverflowRatio || found[0].Column != 3 {
+		t.Errorf("Expected only the long Japanese text to be flagged, not the ones next to nothing but markup, got %v", found)
+	}
+	t.Log("Ratio works...")
+
+	issues, err = CheckOverflow(gameFiles, OverflowOptions{Limit: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found = menu(issues)
+	if len(found) != 3 || found[0].Language != "English" || found[1].Language != "English" || found[2].Key != "long" || found[2].Code != CodeOverflowLimit {
+		t.Errorf("Expected both English Potions and the long Japanese text to be flagged, got %v", found)
+	}
+
+	t.Log("CheckOverflow Passed!")
+}
@@ -1,17 +1,23 @@
 package parser
 
 import (
-	"encoding/csv"
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strconv"
+	"strings"
 	"sync"
 	"unicode"
 )
 
-// TODO: Replace for dynamic lookup inside the Data folder,
-// categorize based on the name.
-// Alternatively, and probably the way we should go about it, use the SheetList.csv file!!!
+// The sheets are now found through SheetList.csv (see LoadSheetManifest),
+// these are only used when an install doesn't have one.
 var KnownNameFiles []string = []string{"HBS", "Item", "Move", "Potion", "Trinkets"}
 var KnownDescriptionFiles []string = []string{"HBS", "Item", "Move", "Potion", "Trinkets"}
 var KnownTitleFiles []string = []string{"Character", "Enemy", "NPC"}
@@ -32,6 +38,7 @@ const (
 	TypeString
 	TypeStringEnum
 	TypeDialogue
+	TypeLanguage
 )
 
 // Interface for any file where text is changed according to the language.
@@ -103,185 +110,342 @@ type Language struct {
 	// The default is 40.
 	// Maps to characterWidthDialog
 	CharacterWidthDialogue int
+
+	// Rows we don't know about (yet), by their label.
+	// They are kept so they can be written back untouched.
+	Extra map[string]string
+}
+
+// Labels of the rows in LanguageEnable.csv, in the order the game ships them
+const (
+	LabelLang                 string = "Lang"
+	LabelDesc                 string = "Desc"
+	LabelEnabled              string = "enabled"
+	LabelExternalFont         string = "externalFont"
+	LabelFont                 string = "font"
+	LabelFullWidth            string = "fullWidth"
+	LabelFontSize             string = "fontSize"
+	LabelOffsetAmount         string = "offsetAmount"
+	LabelOffsetAmountFancy    string = "offsetAmountFancy"
+	LabelOffsetAmountDialog   string = "offsetAmountDialog"
+	LabelCharacterWidth       string = "characterWidth"
+	LabelCharacterWidthFancy  string = "characterWidthFancy"
+	LabelCharacterWidthDialog string = "characterWidthDialog"
+)
+
+var languageLabels []string = []string{
+	LabelLang, LabelDesc, LabelEnabled, LabelExternalFont, LabelFont, LabelFullWidth, LabelFontSize,
+	LabelOffsetAmount, LabelOffsetAmountFancy, LabelOffsetAmountDialog,
+	LabelCharacterWidth, LabelCharacterWidthFancy, LabelCharacterWidthDialog,
+}
+
+// A Language with every field set to its default.
+// These are also used for rows missing from LanguageEnable.csv.
+func NewLanguage(name string) Language {
+	return Language{
+		Name:                   name,
+		NativeName:             name,
+		Enabled:                true,
+		ExternalFont:           false,
+		FullWidth:              false,
+		FontSize:               55,
+		OffsetAmount:           3,
+		OffsetAmountFancy:      0,
+		OffsetAmountDialog:     0,
+		CharacterWidth:         40,
+		CharacterWidthFancy:    56,
+		CharacterWidthDialogue: 40,
+	}
+}
+
+// Sets the field a row of LanguageEnable.csv maps to
+func (l *Language) setField(label, value string) error {
+	var err error
+
+	switch label {
+	case LabelLang:
+		l.Name = value
+	case LabelDesc:
+		l.NativeName = value
+	case LabelEnabled:
+		l.Enabled, err = strconv.ParseBool(value)
+	case LabelExternalFont:
+		l.ExternalFont, err = strconv.ParseBool(value)
+	case LabelFont:
+		l.FontName = value
+	case LabelFullWidth:
+		l.FullWidth, err = strconv.ParseBool(value)
+	case LabelFontSize:
+		l.FontSize, err = strconv.Atoi(value)
+	case LabelOffsetAmount:
+		l.OffsetAmount, err = strconv.Atoi(value)
+	case LabelOffsetAmountFancy:
+		l.OffsetAmountFancy, err = strconv.Atoi(value)
+	case LabelOffsetAmountDialog:
+		l.OffsetAmountDialog, err = strconv.Atoi(value)
+	case LabelCharacterWidth:
+		l.CharacterWidth, err = strconv.Atoi(value)
+	case LabelCharacterWidthFancy:
+		l.CharacterWidthFancy, err = strconv.Atoi(value)
+	case LabelCharacterWidthDialog:
+		l.CharacterWidthDialogue, err = strconv.Atoi(value)
+	default:
+		if l.Extra == nil {
+			l.Extra = make(map[string]string)
+		}
+		l.Extra[label] = value
+	}
+
+	return err
+}
+
+// Text of the field a row maps to, reusing the original text in the layout if the value didn't change
+func (l *Language) field(label string, layout *csvLayout, r, c int) string {
+	switch label {
+	case LabelLang:
+		return l.Name
+	case LabelDesc:
+		return l.NativeName
+	case LabelEnabled:
+		return layout.boolField(r, c, l.Enabled)
+	case LabelExternalFont:
+		return layout.boolField(r, c, l.ExternalFont)
+	case LabelFont:
+		return l.FontName
+	case LabelFullWidth:
+		return layout.boolField(r, c, l.FullWidth)
+	case LabelFontSize:
+		return layout.intField(r, c, l.FontSize)
+	case LabelOffsetAmount:
+		return layout.intField(r, c, l.OffsetAmount)
+	case LabelOffsetAmountFancy:
+		return layout.intField(r, c, l.OffsetAmountFancy)
+	case LabelOffsetAmountDialog:
+		return layout.intField(r, c, l.OffsetAmountDialog)
+	case LabelCharacterWidth:
+		return layout.intField(r, c, l.CharacterWidth)
+	case LabelCharacterWidthFancy:
+		return layout.intField(r, c, l.CharacterWidthFancy)
+	case LabelCharacterWidthDialog:
+		return layout.intField(r, c, l.CharacterWidthDialogue)
+	}
+
+	return l.Extra[label]
 }
 
 // Struct for the file where you set language data
 type LanguageFile struct {
 	File      *os.File
 	Languages []Language
+
+	layout *csvLayout
 }
 
+// Reads LanguageEnable.csv.
+// Rows are matched by the label in their first column, so their order doesn't matter,
+// rows we don't know about end up in Language.Extra,
+// and rows that aren't there leave the defaults from NewLanguage.
 func (lf *LanguageFile) Parse() error {
 	if lf.File == nil {
-		return errors.New("LanguageFile has no File referenced")
+		return noFile(TypeLanguage)
 	}
 
-	records, err := parseFile(lf.File)
+	records, layout, err := parseFileLayout(lf.File)
 	if err != nil {
-		return err
+		return parseFailure(err, lf.File.Name(), TypeLanguage, layout)
 	}
+	lf.layout = layout
 
-	lf.Languages = make([]Language, len(records[0])-1)
-
-	// Yes I know this can all be done in a more compact way
-	// But I wanted to make it clearer and less 'arcane'
-	// This will however make it 10 times more painful to edit if anything changes
-	// Unless you are smart with search and replace (Diamon: I use neovim btw)
-	for i, language := range records[0] {
-		if i == 0 {
-			continue
-		}
-
-		lf.Languages[i-1].Name = language
+	// The Lang row says how many languages there are
+	header := languageHeaderRow(records)
+	if header < 0 {
+		return parseFailure(errors.New("there is no "+LabelLang+" row"), lf.File.Name(), TypeLanguage, layout)
 	}
+	names := records[header][1:]
 
-	for i, desc := range records[1] {
-		if i == 0 {
-			continue
-		}
-
-		lf.Languages[i-1].NativeName = desc
+	lf.Languages = make([]Language, len(names))
+	for i, name := range names {
+		lf.Languages[i] = NewLanguage(name)
 	}
 
-	for i, enabled := range records[2] {
-		if i == 0 {
+	for r, record := range records {
+		label := record[0]
+
+		// Spacers
+		if label == "" {
 			continue
 		}
 
-		val, err := strconv.ParseBool(enabled)
-		if err != nil {
-			return err
-		}
+		for i := range lf.Languages {
+			// Rows too short keep the default
+			if i+1 >= len(record) {
+				break
+			}
 
-		lf.Languages[i-1].Enabled = val
+			if err = lf.Languages[i].setField(label, record[i+1]); err != nil {
+				return parseFailure(&ParseError{Row: r + 1, Column: i + 2, Err: err}, lf.File.Name(), TypeLanguage, layout)
+			}
+		}
 	}
 
-	for i, externalFont := range records[3] {
-		if i == 0 {
-			continue
-		}
+	return nil
+}
 
-		val, err := strconv.ParseBool(externalFont)
-		if err != nil {
-			return err
+// Index of the Lang row, which names the languages, or -1 if there isn't one
+func languageHeaderRow(records [][]string) int {
+	for r, record := range records {
+		if record[0] == LabelLang {
+			return r
 		}
-
-		lf.Languages[i-1].ExternalFont = val
 	}
 
-	for i, font := range records[4] {
-		if i == 0 {
-			continue
-		}
+	return -1
+}
 
-		lf.Languages[i-1].FontName = font
+// Writes the languages back to LanguageEnable.csv.
+// Nothing is written unless every language passes Validate.
+func (lf *LanguageFile) Update() error {
+	if lf.File == nil {
+		return noFile(TypeLanguage)
 	}
 
-	for i, fullWidth := range records[5] {
-		if i == 0 {
-			continue
-		}
-
-		val, err := strconv.ParseBool(fullWidth)
-		if err != nil {
-			return err
-		}
+	if err := lf.Validate(); err != nil {
+		return err
+	}
 
-		lf.Languages[i-1].FullWidth = val
+	data, err := lf.encode()
+	if err != nil {
+		return err
 	}
 
-	for i, fontSize := range records[6] {
-		if i == 0 {
-			continue
-		}
+	return writeGameFile(lf.File.Name(), data)
+}
 
-		val, err := strconv.Atoi(fontSize)
-		if err != nil {
-			return err
-		}
+// A Language field the game wouldn't be happy with
+type LanguageFieldError struct {
+	Language string
 
-		lf.Languages[i-1].FontSize = val
-	}
+	// Name of the row in LanguageEnable.csv
+	Field string
 
-	for i, offsetAmount := range records[7] {
-		if i == 0 {
-			continue
-		}
+	Reason string
+}
 
-		val, err := strconv.Atoi(offsetAmount)
-		if err != nil {
-			return err
-		}
+func (e *LanguageFieldError) Error() string {
+	return fmt.Sprintf("Language %q has an invalid %v: %v", e.Language, e.Field, e.Reason)
+}
 
-		lf.Languages[i-1].OffsetAmount = val
+// Checks every field can be written as is.
+// All problems are returned together, each one as a *LanguageFieldError.
+func (l *Language) Validate() error {
+	errs := make([]error, 0)
+	invalid := func(field, reason string) {
+		errs = append(errs, &LanguageFieldError{Language: l.Name, Field: field, Reason: reason})
 	}
 
-	for i, offsetAmountFancy := range records[8] {
-		if i == 0 {
-			continue
-		}
+	if l.Name == "" {
+		invalid("Lang", "it can't be empty")
+	}
+	if l.ExternalFont && l.FontName == "" {
+		invalid("font", "externalFont is set but there is no font")
+	}
+	if l.FontSize <= 0 {
+		invalid("fontSize", fmt.Sprintf("%d is not a positive size", l.FontSize))
+	}
+	if l.CharacterWidth <= 0 {
+		invalid("characterWidth", fmt.Sprintf("%d is not a positive width", l.CharacterWidth))
+	}
+	if l.CharacterWidthFancy <= 0 {
+		invalid("characterWidthFancy", fmt.Sprintf("%d is not a positive width", l.CharacterWidthFancy))
+	}
+	if l.CharacterWidthDialogue <= 0 {
+		invalid("characterWidthDialog", fmt.Sprintf("%d is not a positive width", l.CharacterWidthDialogue))
+	}
 
-		val, err := strconv.Atoi(offsetAmountFancy)
-		if err != nil {
-			return err
-		}
+	return errors.Join(errs...)
+}
 
-		lf.Languages[i-1].OffsetAmountFancy = val
-	}
+// Checks every language, and that no two share a name
+func (lf *LanguageFile) Validate() error {
+	errs := make([]error, 0)
+	seen := make(map[string]bool)
 
-	for i, offsetAmountDialog := range records[9] {
-		if i == 0 {
-			continue
+	for i := range lf.Languages {
+		if err := lf.Languages[i].Validate(); err != nil {
+			errs = append(errs, err)
 		}
 
-		val, err := strconv.Atoi(offsetAmountDialog)
-		if err != nil {
-			return err
+		name := lf.Languages[i].Name
+		if seen[name] && name != "" {
+			errs = append(errs, &LanguageFieldError{Language: name, Field: "Lang", Reason: "there is another language with the same name"})
 		}
-
-		lf.Languages[i-1].OffsetAmountDialog = val
+		seen[name] = true
 	}
 
-	for i, characterWidth := range records[10] {
-		if i == 0 {
-			continue
-		}
-
-		val, err := strconv.Atoi(characterWidth)
-		if err != nil {
-			return err
-		}
+	return errors.Join(errs...)
+}
 
-		lf.Languages[i-1].CharacterWidth = val
+// Turns the languages back into CSV data, keeping the rows in the order they were read.
+// Known rows that weren't in the file are added at the end,
+// but only if some language doesn't have the default value for them.
+func (lf *LanguageFile) encode() ([]byte, error) {
+	if lf.layout == nil || len(lf.layout.values) == 0 {
+		return nil, errors.New("LanguageFile must be parsed before it can be written")
 	}
 
-	for i, characterWidthFancy := range records[11] {
-		if i == 0 {
+	l := lf.layout
+	records := make([][]string, 0, len(l.values))
+	present := make(map[string]bool)
+
+	for r, row := range l.values {
+		label := row[0]
+		present[label] = true
+
+		if label == "" {
+			records = append(records, row)
 			continue
 		}
 
-		val, err := strconv.Atoi(characterWidthFancy)
-		if err != nil {
-			return err
+		record := []string{label}
+		for i := range lf.Languages {
+			record = append(record, lf.Languages[i].field(label, l, r, i+1))
+		}
+
+		// Rows that were too short stay that way, as long as they would only grow with defaults
+		for len(record) > len(row) {
+			last := len(record) - 1
+			defaultLanguage := NewLanguage(lf.Languages[last-1].Name)
+			if record[last] != defaultLanguage.field(label, l, r, last) {
+				break
+			}
+			record = record[:last]
 		}
 
-		lf.Languages[i-1].CharacterWidthFancy = val
+		records = append(records, record)
 	}
 
-	for i, characterWidthDialog := range records[12] {
-		if i == 0 {
+	for _, label := range languageLabels {
+		if present[label] {
 			continue
 		}
 
-		val, err := strconv.Atoi(characterWidthDialog)
-		if err != nil {
-			return err
+		record := []string{label}
+		needed := false
+		for i := range lf.Languages {
+			value := lf.Languages[i].field(label, l, len(l.values), i+1)
+			defaultLanguage := NewLanguage(lf.Languages[i].Name)
+			if value != defaultLanguage.field(label, l, len(l.values), i+1) {
+				needed = true
+			}
+			record = append(record, value)
 		}
 
-		lf.Languages[i-1].CharacterWidthDialogue = val
+		if needed {
+			records = append(records, record)
+		}
 	}
 
-	return nil
+	return writeCSV(records, l), nil
 }
 
 // The individual translation for each key
@@ -297,6 +461,10 @@ type KeyLevelStrings struct {
 }
 
 func ParseKeyLevelStrings(sheet *[]KeyLevelStrings, records [][]string) error {
+	if len(records) == 0 {
+		return &ParseError{Err: errors.New("sheet has no header")}
+	}
+
 	langs := make([]string, 0)
 
 	// Populate languages
@@ -313,12 +481,19 @@ func ParseKeyLevelStrings(sheet *[]KeyLevelStrings, records [][]string) error {
 			continue
 		}
 
+		if len(records[row]) < 2 {
+			return &ParseError{Row: row + 1, Err: errors.New("row has no level")}
+		}
+		if len(records[row]) > len(records[0]) {
+			return &ParseError{Row: row + 1, Column: len(records[0]) + 1, Err: errors.New("row has more fields than the header")}
+		}
+
 		newKLS := &KeyLevelStrings{}
 
 		newKLS.Key = records[row][0]
 		val, err := strconv.Atoi(records[row][1])
 		if err != nil {
-			return err
+			return &ParseError{Row: row + 1, Column: 2, Err: fmt.Errorf("level %q is not a number: %w", records[row][1], err)}
 		}
 		newKLS.Level = val
 
@@ -338,6 +513,36 @@ func ParseKeyLevelStrings(sheet *[]KeyLevelStrings, records [][]string) error {
 	return nil
 }
 
+// Turns the rows back into CSV data.
+// The layout is the one read alongside the rows, it provides the header
+// and keeps unchanged fields exactly as they were in the file.
+func encodeKeyLevelStrings(sheet []KeyLevelStrings, layout *csvLayout) ([]byte, error) {
+	if layout == nil || len(layout.values) == 0 {
+		return nil, errors.New("Sheet has no header, it must be parsed before it can be written")
+	}
+
+	records := make([][]string, 0, len(sheet)+1)
+	records = append(records, layout.values[0])
+
+	for i, kls := range sheet {
+		row := i + 1
+
+		if kls.Key == "" {
+			records = append(records, layout.spacer(row))
+			continue
+		}
+
+		record := []string{kls.Key, layout.intField(row, 1, kls.Level)}
+		for _, translation := range kls.Strings {
+			record = append(record, translation.String)
+		}
+
+		records = append(records, record)
+	}
+
+	return writeCSV(records, layout), nil
+}
+
 // A translation with the format key,language
 type KeyStrings struct {
 	Key     string
@@ -345,6 +550,10 @@ type KeyStrings struct {
 }
 
 func ParseKeyStrings(sheet *[]KeyStrings, records [][]string) error {
+	if len(records) == 0 {
+		return &ParseError{Err: errors.New("sheet has no header")}
+	}
+
 	langs := make([]string, 0)
 
 	// Populate languages
@@ -360,6 +569,11 @@ func ParseKeyStrings(sheet *[]KeyStrings, records [][]string) error {
 			*sheet = append(*sheet, *emptyKS)
 			continue
 		}
+
+		if len(records[row]) > len(records[0]) {
+			return &ParseError{Row: row + 1, Column: len(records[0]) + 1, Err: errors.New("row has more fields than the header")}
+		}
+
 		newKS := &KeyStrings{}
 
 		newKS.Key = records[row][0]
@@ -380,6 +594,34 @@ func ParseKeyStrings(sheet *[]KeyStrings, records [][]string) error {
 	return nil
 }
 
+// Turns the rows back into CSV data, see encodeKeyLevelStrings
+func encodeKeyStrings(sheet []KeyStrings, layout *csvLayout) ([]byte, error) {
+	if layout == nil || len(layout.values) == 0 {
+		return nil, errors.New("Sheet has no header, it must be parsed before it can be written")
+	}
+
+	records := make([][]string, 0, len(sheet)+1)
+	records = append(records, layout.values[0])
+
+	for i, ks := range sheet {
+		row := i + 1
+
+		if ks.Key == "" {
+			records = append(records, layout.spacer(row))
+			continue
+		}
+
+		record := []string{ks.Key}
+		for _, translation := range ks.Strings {
+			record = append(record, translation.String)
+		}
+
+		records = append(records, record)
+	}
+
+	return writeCSV(records, layout), nil
+}
+
 // Struct for NameSheets.
 // No I won't make the Strings variable into a pointer to an array,
 // not for my own sanity, but for anyone who wishes to use it later.
@@ -387,105 +629,158 @@ func ParseKeyStrings(sheet *[]KeyStrings, records [][]string) error {
 type NameSheet struct {
 	File    *os.File
 	Strings []KeyLevelStrings
+
+	layout *csvLayout
 }
 
 func (ns *NameSheet) Parse() error {
 	if ns.File == nil {
-		return errors.New("NameSheet has no File referenced")
+		return noFile(TypeName)
 	}
 
-	records, err := parseFile(ns.File)
+	records, layout, err := parseFileLayout(ns.File)
 	if err != nil {
-		return err
+		return parseFailure(err, ns.File.Name(), TypeName, layout)
 	}
+	ns.layout = layout
 
 	if err = ParseKeyLevelStrings(&ns.Strings, records); err != nil {
-		return err
+		return parseFailure(err, ns.File.Name(), TypeName, layout)
 	}
 
 	return nil
 }
 
+// Writes the sheet back to its file.
+// Rows that did not change are written exactly as they were read.
 func (ns *NameSheet) Update() error {
-	return errors.New("Not yet implemented")
+	if ns.File == nil {
+		return noFile(TypeName)
+	}
+
+	data, err := encodeKeyLevelStrings(ns.Strings, ns.layout)
+	if err != nil {
+		return err
+	}
+
+	return writeGameFile(ns.File.Name(), data)
 }
 
 // Struct for DescriptionSheets
 type DescriptionSheet struct {
 	File    *os.File
 	Strings []KeyLevelStrings
+
+	layout *csvLayout
 }
 
 func (ds *DescriptionSheet) Parse() error {
 	if ds.File == nil {
-		return errors.New("DescriptionSheet has no File referenced")
+		return noFile(TypeDescription)
 	}
 
-	records, err := parseFile(ds.File)
+	records, layout, err := parseFileLayout(ds.File)
 	if err != nil {
-		return err
+		return parseFailure(err, ds.File.Name(), TypeDescription, layout)
 	}
+	ds.layout = layout
 
 	if err = ParseKeyLevelStrings(&ds.Strings, records); err != nil {
-		return err
+		return parseFailure(err, ds.File.Name(), TypeDescription, layout)
 	}
 
 	return nil
 }
 
-func (ns *DescriptionSheet) Update() error {
-	return errors.New("Not yet implemented")
+func (ds *DescriptionSheet) Update() error {
+	if ds.File == nil {
+		return noFile(TypeDescription)
+	}
+
+	data, err := encodeKeyLevelStrings(ds.Strings, ds.layout)
+	if err != nil {
+		return err
+	}
+
+	return writeGameFile(ds.File.Name(), data)
 }
 
 // Struct for TitleSheets
 type TitleSheet struct {
 	File    *os.File
 	Strings []KeyLevelStrings
+
+	layout *csvLayout
 }
 
 func (ts *TitleSheet) Parse() error {
 	if ts.File == nil {
-		return errors.New("TitleSheet has no File referenced")
+		return noFile(TypeTitle)
 	}
 
-	records, err := parseFile(ts.File)
+	records, layout, err := parseFileLayout(ts.File)
 	if err != nil {
-		return err
+		return parseFailure(err, ts.File.Name(), TypeTitle, layout)
 	}
+	ts.layout = layout
 
 	if err = ParseKeyLevelStrings(&ts.Strings, records); err != nil {
-		return err
+		return parseFailure(err, ts.File.Name(), TypeTitle, layout)
 	}
 
 	return nil
 }
 
-func (ns *TitleSheet) Update() error {
-	return errors.New("Not yet implemented")
+func (ts *TitleSheet) Update() error {
+	if ts.File == nil {
+		return noFile(TypeTitle)
+	}
+
+	data, err := encodeKeyLevelStrings(ts.Strings, ts.layout)
+	if err != nil {
+		return err
+	}
+
+	return writeGameFile(ts.File.Name(), data)
 }
 
 // Struct for StringSheets
 type StringSheet struct {
 	File    *os.File
 	Strings []KeyStrings
+
+	layout *csvLayout
 }
 
 func (ss *StringSheet) Parse() error {
 	if ss.File == nil {
-		return errors.New("StringSheet has no File referenced")
+		return noFile(TypeString)
 	}
 
-	records, err := parseFile(ss.File)
+	records, layout, err := parseFileLayout(ss.File)
+	if err != nil {
+		return parseFailure(err, ss.File.Name(), TypeString, layout)
+	}
+	ss.layout = layout
 
 	if err = ParseKeyStrings(&ss.Strings, records); err != nil {
-		return err
+		return parseFailure(err, ss.File.Name(), TypeString, layout)
 	}
 
 	return nil
 }
 
 func (ss *StringSheet) Update() error {
-	return errors.New("Not yet implemented")
+	if ss.File == nil {
+		return noFile(TypeString)
+	}
+
+	data, err := encodeKeyStrings(ss.Strings, ss.layout)
+	if err != nil {
+		return err
+	}
+
+	return writeGameFile(ss.File.Name(), data)
 }
 
 // Struct for StringEnumSheet.
@@ -494,26 +789,45 @@ func (ss *StringSheet) Update() error {
 type StringEnumSheet struct {
 	File    *os.File
 	Strings []KeyStrings
+
+	layout *csvLayout
 }
 
 func (sse *StringEnumSheet) Parse() error {
 	if sse.File == nil {
-		return errors.New("StringSheet has no File referenced")
+		return noFile(TypeStringEnum)
 	}
 
-	records, err := parseFile(sse.File)
+	records, layout, err := parseFileLayout(sse.File)
+	if err != nil {
+		return parseFailure(err, sse.File.Name(), TypeStringEnum, layout)
+	}
+	sse.layout = layout
 
 	if err = ParseKeyStrings(&sse.Strings, records); err != nil {
-		return err
+		return parseFailure(err, sse.File.Name(), TypeStringEnum, layout)
 	}
 
 	return nil
 }
 
-func (ss *StringEnumSheet) Update() error {
-	return errors.New("Not yet implemented")
+func (sse *StringEnumSheet) Update() error {
+	if sse.File == nil {
+		return noFile(TypeStringEnum)
+	}
+
+	data, err := encodeKeyStrings(sse.Strings, sse.layout)
+	if err != nil {
+		return err
+	}
+
+	return writeGameFile(sse.File.Name(), data)
 }
 
+// A line of a dialogue script.
+// FlagScript and ExpressionVar0 hold an int when the field is a number,
+// and the original string otherwise.
+// Empty rows have both set to nil.
 type DialogueStrings struct {
 	Type           int
 	FlagScript     any
@@ -522,6 +836,10 @@ type DialogueStrings struct {
 }
 
 func ParseDialogueStrings(sheet *[]DialogueStrings, records [][]string) error {
+	if len(records) == 0 {
+		return &ParseError{Err: errors.New("dialogue has no header")}
+	}
+
 	langs := make([]string, 0)
 
 	// Populate languages
@@ -538,35 +856,23 @@ func ParseDialogueStrings(sheet *[]DialogueStrings, records [][]string) error {
 			continue
 		}
 
+		if len(records[row]) < 3 {
+			return &ParseError{Row: row + 1, Column: len(records[row]) + 1, Err: errors.New("row is missing fields")}
+		}
+		if len(records[row]) > len(records[0]) {
+			return &ParseError{Row: row + 1, Column: len(records[0]) + 1, Err: errors.New("row has more fields than the header")}
+		}
+
 		newDS := &DialogueStrings{}
 
 		val, err := strconv.Atoi(records[row][0])
 		if err != nil {
-			return err
+			return &ParseError{Row: row + 1, Column: 1, Err: err}
 		}
 
 		newDS.Type = val
-		if unicode.IsDigit(rune(records[row][1][0])) {
-			val, err = strconv.Atoi(records[row][1])
-			if err != nil {
-				return err
-			}
-
-			newDS.FlagScript = val
-		} else {
-			newDS.FlagScript = records[row][1]
-		}
-
-		if unicode.IsDigit(rune(records[row][2][0])) {
-			val, err = strconv.Atoi(records[row][2])
-			if err != nil {
-				return err
-			}
-
-			newDS.ExpressionVar0 = val
-		} else {
-			newDS.ExpressionVar0 = records[row][2]
-		}
+		newDS.FlagScript = parseIntOrString(records[row][1])
+		newDS.ExpressionVar0 = parseIntOrString(records[row][2])
 
 		for lang := 0; lang+3 < len(records[row]); lang++ {
 			newTranslation := &Translation{
@@ -583,30 +889,99 @@ func ParseDialogueStrings(sheet *[]DialogueStrings, records [][]string) error {
 	return nil
 }
 
+// Numbers become ints, anything else (including something like "1a") stays a string
+func parseIntOrString(field string) any {
+	if field != "" && unicode.IsDigit(rune(field[0])) {
+		if val, err := strconv.Atoi(field); err == nil {
+			return val
+		}
+	}
+
+	return field
+}
+
+// Text for a field parsed with parseIntOrString
+func (l *csvLayout) intOrStringField(r, c int, value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case int:
+		return l.intField(r, c, v)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Turns the rows back into CSV data, see encodeKeyLevelStrings
+func encodeDialogueStrings(sheet []DialogueStrings, layout *csvLayout) ([]byte, error) {
+	if layout == nil || len(layout.values) == 0 {
+		return nil, errors.New("Dialogue has no header, it must be parsed before it can be written")
+	}
+
+	records := make([][]string, 0, len(sheet)+1)
+	records = append(records, layout.values[0])
+
+	for i, ds := range sheet {
+		row := i + 1
+
+		if ds.FlagScript == nil && ds.ExpressionVar0 == nil {
+			records = append(records, layout.spacer(row))
+			continue
+		}
+
+		record := []string{
+			layout.intField(row, 0, ds.Type),
+			layout.intOrStringField(row, 1, ds.FlagScript),
+			layout.intOrStringField(row, 2, ds.ExpressionVar0),
+		}
+		for _, translation := range ds.Translations {
+			record = append(record, translation.String)
+		}
+
+		records = append(records, record)
+	}
+
+	return writeCSV(records, layout), nil
+}
+
 type DialogueFile struct {
 	File    *os.File
 	Strings []DialogueStrings
+
+	layout *csvLayout
 }
 
 func (df *DialogueFile) Parse() error {
 	if df.File == nil {
-		return errors.New("DialogueFile has no File referenced")
+		return noFile(TypeDialogue)
 	}
 
-	records, err := parseFile(df.File)
+	records, layout, err := parseFileLayout(df.File)
 	if err != nil {
-		return err
+		return parseFailure(err, df.File.Name(), TypeDialogue, layout)
 	}
+	df.layout = layout
 
 	if err = ParseDialogueStrings(&df.Strings, records); err != nil {
-		return err
+		return parseFailure(err, df.File.Name(), TypeDialogue, layout)
 	}
 
 	return nil
 }
 
 func (df *DialogueFile) Update() error {
-	return errors.New("Not yet implemented")
+	if df.File == nil {
+		return noFile(TypeDialogue)
+	}
+
+	data, err := encodeDialogueStrings(df.Strings, df.layout)
+	if err != nil {
+		return err
+	}
+
+	return writeGameFile(df.File.Name(), data)
 }
 
 // Files used to manage language data
@@ -614,6 +989,38 @@ type LanguageFiles struct {
 	Languages LanguageFile
 	Sheets    []TranslationFile
 	Dialogues []TranslationFile
+
+	// Anything odd found while loading that wasn't worth failing over
+	Warnings []error
+}
+
+// Lists every dialogue script in the Dialog folder.
+// KnownDialogueFiles is only used to warn about scripts that went missing.
+func findDialogueFiles(gamePath string) ([]string, []error, error) {
+	entries, err := os.ReadDir(gamePath + "/Dialog")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	paths := make([]string, 0, len(entries))
+	found := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".csv") {
+			continue
+		}
+
+		paths = append(paths, gamePath+"/Dialog/"+entry.Name())
+		found[strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))] = true
+	}
+
+	warnings := make([]error, 0)
+	for _, name := range KnownDialogueFiles {
+		if !found[name] {
+			warnings = append(warnings, fmt.Errorf("Known dialogue file %v.csv was not found in %v", name, gamePath+"/Dialog"))
+		}
+	}
+
+	return paths, warnings, nil
 }
 
 // Used to initially parse CSV files
@@ -622,73 +1029,191 @@ type LanguageFiles struct {
 // Probably not,
 // but who knows
 func parseFile(file *os.File) ([][]string, error) {
-	r := csv.NewReader(file)
+	records, _, err := parseFileLayout(file)
+
+	return records, err
+}
 
-	records, err := r.ReadAll()
+// Same as parseFile, but also returns what is needed to write the file back untouched
+func parseFileLayout(file *os.File) ([][]string, *csvLayout, error) {
+	if file == nil {
+		return nil, nil, errors.New("There is no file to parse")
+	}
+
+	data, err := io.ReadAll(file)
 	if err != nil {
-		return records, err
+		return nil, nil, err
 	}
 
-	return records, nil
+	return readCSV(data)
 }
 
-// Concurrently opens and parses the language files.
-func parseLanguageFileConcurrent(filePath string, fileType FileType, fileCollection *chan *TranslationFile, wg *sync.WaitGroup) {
-	defer wg.Done()
-    defer func() {
-        if r := recover(); r != nil {}
-    }()
+// Replaces the contents of a game file.
+// The original is always backed up first (see BackupDir), and the write is atomic.
+// Every write to the game files must go through here.
+func writeGameFile(path string, data []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+
+		// Nothing to do if the contents are the same
+		if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, data) {
+			return nil
+		}
+	}
+
+	if err := backupFile(path); err != nil {
+		return fmt.Errorf("Refusing to write %v, it could not be backed up: %w", path, err)
+	}
 
-	file, err := os.OpenFile(filePath, os.O_RDWR, 0644)
+	return WriteFileAtomic(path, data, mode)
+}
+
+// Writes the data to a temporary file next to the target and then renames it over it,
+// so nothing ever sees a half-written file.
+// Nothing is backed up, game files go through writeGameFile instead.
+func WriteFileAtomic(path string, data []byte, mode os.FileMode) error {
+	tmp, err := stageFile(path, data, mode)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	defer file.Close()
+	defer os.Remove(tmp)
 
-	var newLangFile TranslationFile
+	return os.Rename(tmp, path)
+}
 
-	switch fileType {
-	case TypeName:
-		newLangFile = &NameSheet{
-			File: file,
+// Writes the data to a temporary file next to path, ready to be renamed over it
+func stageFile(path string, data []byte, mode os.FileMode) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), mode)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	return tmp.Name(), nil
+}
+
+// A file waiting to be written by writeGameFiles
+type pendingWrite struct {
+	path string
+	data []byte
+
+	// Whether the file may not exist yet, like when restoring a backup
+	create bool
+
+	// Whether the file is removed instead of written
+	remove bool
+}
+
+// Replaces the contents of several game files at once, either all of them change or none do.
+// Everything is backed up and staged next to its target before the first file is replaced,
+// and if replacing one fails, the ones already replaced are put back.
+func writeGameFiles(writes []pendingWrite) error {
+	type stagedWrite struct {
+		path     string
+		tmp      string
+		original []byte
+		mode     os.FileMode
+		missing  bool
+		remove   bool
+	}
+
+	staged := make([]stagedWrite, 0, len(writes))
+	defer func() {
+		for _, s := range staged {
+			if s.tmp != "" {
+				os.Remove(s.tmp)
+			}
 		}
-	case TypeDescription:
-		newLangFile = &DescriptionSheet{
-			File: file,
+	}()
+
+	for _, write := range writes {
+		original, err := os.ReadFile(write.path)
+		missing := errors.Is(err, fs.ErrNotExist)
+		if err != nil && !(missing && (write.create || write.remove)) {
+			return err
 		}
-	case TypeTitle:
-		newLangFile = &TitleSheet{
-			File: file,
+
+		// Nothing to do if the contents are the same
+		if write.remove && missing || !write.remove && !missing && bytes.Equal(original, write.data) {
+			continue
 		}
-	case TypeString:
-		newLangFile = &StringSheet{
-			File: file,
+
+		mode := os.FileMode(0644)
+		if !missing {
+			info, err := os.Stat(write.path)
+			if err != nil {
+				return err
+			}
+			mode = info.Mode().Perm()
 		}
-	case TypeStringEnum:
-		newLangFile = &StringEnumSheet{
-			File: file,
+
+		if err = backupFile(write.path); err != nil {
+			return fmt.Errorf("Refusing to write %v, it could not be backed up: %w", write.path, err)
 		}
-	case TypeDialogue:
-		newLangFile = &DialogueFile{
-			File: file,
+
+		s := stagedWrite{
+			path:     write.path,
+			original: original,
+			mode:     mode,
+			missing:  missing,
+			remove:   write.remove,
 		}
-	default:
-		panic(errors.New("A goroutine failed to parse file: " + file.Name()))
+		if !write.remove {
+			if s.tmp, err = stageFile(write.path, write.data, mode); err != nil {
+				return err
+			}
+		}
+
+		staged = append(staged, s)
 	}
 
-	if err = newLangFile.Parse(); err != nil {
-		panic(err)
+	for i, s := range staged {
+		var err error
+		if s.remove {
+			err = os.Remove(s.path)
+		} else {
+			err = os.Rename(s.tmp, s.path)
+		}
+
+		if err != nil {
+			// Put back whatever was already replaced
+			for _, done := range staged[:i] {
+				var rollbackErr error
+				if done.missing {
+					rollbackErr = os.Remove(done.path)
+				} else {
+					rollbackErr = WriteFileAtomic(done.path, done.original, done.mode)
+				}
+				if rollbackErr != nil {
+					return fmt.Errorf("Failed to write %v (%w), and then failed to put back %v: %v", s.path, err, done.path, rollbackErr)
+				}
+			}
+			return err
+		}
 	}
 
-	// Add the file to the channel
-	*fileCollection <- &newLangFile
+	return nil
 }
 
 func parseLanguageFile(filePath string, fileType FileType) (*TranslationFile, error) {
 	var newTranslationFile TranslationFile
     file, err := os.OpenFile(filePath, os.O_RDWR, 0644)
 	if err != nil {
-		return &newTranslationFile, err
+		return &newTranslationFile, parseFailure(err, filePath, fileType, nil)
 	}
 	defer file.Close()
 
@@ -718,7 +1243,7 @@ func parseLanguageFile(filePath string, fileType FileType) (*TranslationFile, er
 			File: file,
 		}
 	default:
-        return &newTranslationFile, errors.New("Failed to parse file: "+file.Name())
+        return &newTranslationFile, parseFailure(errors.New("unknown file type"), file.Name(), fileType, nil)
 	}
 
 	if err = newTranslationFile.Parse(); err != nil {
@@ -734,7 +1259,7 @@ func ParseGameFiles(gamePath string) (LanguageFiles, error) {
 	// Open and parse the Languages file
 	file, err := os.OpenFile(gamePath+"/Data/LanguageEnable.csv", os.O_RDWR, 0644)
 	if err != nil {
-		return languageFiles, err
+		return languageFiles, parseFailure(err, gamePath+"/Data/LanguageEnable.csv", TypeLanguage, nil)
 	}
 	defer file.Close()
 
@@ -749,78 +1274,110 @@ func ParseGameFiles(gamePath string) (LanguageFiles, error) {
 	// Add the Languages file
 	languageFiles.Languages = *languageFile
 
-	// Open and parse the Name files
-	for _, name := range KnownNameFiles {
-        nameSheet, err := parseLanguageFile(gamePath+"/Data/Names_"+name+".csv", TypeName)
-		if err != nil {
-			return languageFiles, err
-		}
-
-		// Add the Names file
-		languageFiles.Sheets = append(languageFiles.Sheets, *nameSheet)
-	}
-
-	// Open and parse the Description files
-	for _, name := range KnownDescriptionFiles {
-        descriptionSheet, err := parseLanguageFile(gamePath+"/Data/Descriptions_"+name+".csv", TypeDescription)
-		if err != nil {
-			return languageFiles, err
-		}
-
-		// Add the Descriptions file
-		languageFiles.Sheets = append(languageFiles.Sheets, *descriptionSheet)
+	// Find out which sheets there are
+	manifest, err := LoadSheetManifest(gamePath)
+	if err != nil {
+		return languageFiles, err
 	}
+	languageFiles.Warnings = append(languageFiles.Warnings, manifest.Warnings()...)
 
-	// Open and parse the Title files
-	for _, name := range KnownTitleFiles {
-        titleSheet, err := parseLanguageFile(gamePath+"/Data/Titles_"+name+".csv", TypeTitle)
+	// Open and parse the sheets, in the order they are listed
+	for _, entry := range manifest.Sheets {
+		sheet, err := parseLanguageFile(entry.Path, entry.Type)
 		if err != nil {
 			return languageFiles, err
 		}
 
-		// Add the Titles file
-		languageFiles.Sheets = append(languageFiles.Sheets, *titleSheet)
+		languageFiles.Sheets = append(languageFiles.Sheets, *sheet)
 	}
 
-	// Open and parse the String files
-	// First do the odd one
-    stringSheet, err := parseLanguageFile(gamePath+"/Data/Strings.csv", TypeString)
+	// Open and parse the Dialogue files
+	dialoguePaths, warnings, err := findDialogueFiles(gamePath)
 	if err != nil {
 		return languageFiles, err
 	}
+	languageFiles.Warnings = append(languageFiles.Warnings, warnings...)
 
-	languageFiles.Sheets = append(languageFiles.Sheets, *stringSheet)
-
-	// Now do the other ones known
-	for _, name := range KnownStringFiles {
-		stringSheet, err = parseLanguageFile(gamePath+"/Data/Strings_"+name+".csv", TypeString)
+	for _, path := range dialoguePaths {
+		dialogueFile, err := parseLanguageFile(path, TypeDialogue)
 		if err != nil {
 			return languageFiles, err
 		}
 
-		languageFiles.Sheets = append(languageFiles.Sheets, *stringSheet)
+		languageFiles.Dialogues = append(languageFiles.Dialogues, *dialogueFile)
 	}
 
-	// Same for the StringEnums
-	for _, name := range KnownStringEnumFiles {
-        stringEnumSheet, err := parseLanguageFile(gamePath+"/Data/Strings_"+name+".csv", TypeStringEnum)
-		if err != nil {
-			return languageFiles, err
+	return languageFiles, nil
+}
+
+// How the concurrent loader behaves
+type ParseOptions struct {
+	// Stop parsing the remaining files as soon as one of them fails.
+	// Otherwise every file is parsed and every failure is reported.
+	StopOnError bool
+
+	// How many files are parsed at the same time.
+	// 0 or less means one per CPU.
+	Workers int
+
+	// Called every time a file is done (parsed, failed or skipped), if set.
+	// Calls never overlap, and done only ever goes up until it reaches total.
+	Progress func(done, total int)
+}
+
+// Reported for files that were never parsed because loading stopped early
+var ErrParseStopped = errors.New("not parsed, loading stopped early")
+
+// Reported when there is no File to parse or write
+var ErrNoFile = errors.New("no File referenced")
+
+// A file to parse, and how it went
+type parseJob struct {
+	path     string
+	fileType FileType
+	file     TranslationFile
+	err      error
+}
+
+// Same as parseLanguageFile, but a panic is turned into an error instead of taking everything down
+func parseLanguageFileSafe(filePath string, fileType FileType) (file TranslationFile, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = parseFailure(fmt.Errorf("parser panicked: %v", r), filePath, fileType, nil)
 		}
+	}()
 
-		languageFiles.Sheets = append(languageFiles.Sheets, *stringEnumSheet)
+	newFile, err := parseLanguageFile(filePath, fileType)
+	if err != nil {
+		return nil, err
 	}
 
-	return languageFiles, nil
+	return *newFile, nil
 }
 
+// Parses every sheet and dialogue at the same time.
+// Every file that fails is reported, all the errors are joined together,
+// and whatever did parse is still returned.
+// Files end up in the same order as with ParseGameFiles, no matter which one finishes first.
 func ParseGameFilesConcurrent(gamePath string) (LanguageFiles, error) {
+	return ParseGameFilesContext(context.Background(), gamePath, ParseOptions{})
+}
+
+// Same as ParseGameFilesConcurrent, but with a limited amount of workers, progress reports,
+// and the option to stop early.
+// Once ctx is done, the files being parsed are finished and the rest are skipped,
+// and context.Cause(ctx) is returned once for all of them, along with any file that failed before that.
+func ParseGameFilesContext(ctx context.Context, gamePath string, opts ParseOptions) (LanguageFiles, error) {
 	var languageFiles LanguageFiles
 
+	if ctx.Err() != nil {
+		return languageFiles, context.Cause(ctx)
+	}
+
 	// Open and parse the Languages file
 	file, err := os.OpenFile(gamePath+"/Data/LanguageEnable.csv", os.O_RDWR, 0644)
 	if err != nil {
-		return languageFiles, err
+		return languageFiles, parseFailure(err, gamePath+"/Data/LanguageEnable.csv", TypeLanguage, nil)
 	}
 	defer file.Close()
 
@@ -835,60 +1392,105 @@ func ParseGameFilesConcurrent(gamePath string) (LanguageFiles, error) {
 	// Add the Languages file
 	languageFiles.Languages = *languageFile
 
-	// Create the Wait Group
-	wg := sync.WaitGroup{}
-
-	// Create the Sheets channel
-    totalSheetCount := len(KnownNameFiles) + len(KnownDescriptionFiles) + len(KnownTitleFiles) + len(KnownStringFiles)+1 + len(KnownStringEnumFiles)
-	sheetChan := make(chan *TranslationFile, totalSheetCount)
-    defer close(sheetChan)
-
-	// Open and parse the Name files
-	for _, name := range KnownNameFiles {
-		wg.Add(1)
-		go parseLanguageFileConcurrent(gamePath+"/Data/Names_"+name+".csv", TypeName, &sheetChan, &wg)
+	// Find out which sheets there are
+	manifest, err := LoadSheetManifest(gamePath)
+	if err != nil {
+		return languageFiles, err
 	}
+	languageFiles.Warnings = append(languageFiles.Warnings, manifest.Warnings()...)
 
-	// Open and parse the Description files
-	for _, name := range KnownDescriptionFiles {
-		wg.Add(1)
-		go parseLanguageFileConcurrent(gamePath+"/Data/Descriptions_"+name+".csv", TypeDescription, &sheetChan, &wg)
+	// Find the Dialogue files
+	dialoguePaths, warnings, err := findDialogueFiles(gamePath)
+	if err != nil {
+		return languageFiles, err
 	}
+	languageFiles.Warnings = append(languageFiles.Warnings, warnings...)
 
-	// Open and parse the Title files
-	for _, name := range KnownTitleFiles {
-		wg.Add(1)
-		go parseLanguageFileConcurrent(gamePath+"/Data/Titles_"+name+".csv", TypeTitle, &sheetChan, &wg)
+	jobs := make([]*parseJob, 0, len(manifest.Sheets)+len(dialoguePaths))
+	for _, entry := range manifest.Sheets {
+		jobs = append(jobs, &parseJob{path: entry.Path, fileType: entry.Type})
+	}
+	for _, path := range dialoguePaths {
+		jobs = append(jobs, &parseJob{path: path, fileType: TypeDialogue})
 	}
 
-	// Open and parse the String files
-	// First do the odd one
-	wg.Add(1)
-	go parseLanguageFileConcurrent(gamePath+"/Data/Strings.csv", TypeString, &sheetChan, &wg)
+	// Cancelled with ErrParseStopped once a file fails, if we were asked to stop
+	parent := ctx
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
 
-	// Now do the other ones known
-	for _, name := range KnownStringFiles {
-		wg.Add(1)
-		go parseLanguageFileConcurrent(gamePath+"/Data/Strings_"+name+".csv", TypeString, &sheetChan, &wg)
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
 	}
 
-	// Same for the StringEnums
-	for _, name := range KnownStringEnumFiles {
+	jobChan := make(chan *parseJob)
+	var progressMutex sync.Mutex
+	done := 0
+
+	// Each worker only touches the job it is on,
+	// and the jobs are already in the same order ParseGameFiles uses
+	wg := sync.WaitGroup{}
+	for range workers {
 		wg.Add(1)
-		go parseLanguageFileConcurrent(gamePath+"/Data/Strings_"+name+".csv", TypeStringEnum, &sheetChan, &wg)
+		go func() {
+			defer wg.Done()
+
+			for job := range jobChan {
+				if ctx.Err() != nil {
+					job.err = parseFailure(context.Cause(ctx), job.path, job.fileType, nil)
+				} else {
+					job.file, job.err = parseLanguageFileSafe(job.path, job.fileType)
+					if job.err != nil && opts.StopOnError {
+						cancel(ErrParseStopped)
+					}
+				}
+
+				if opts.Progress != nil {
+					progressMutex.Lock()
+					done++
+					opts.Progress(done, len(jobs))
+					progressMutex.Unlock()
+				}
+			}
+		}()
 	}
 
-    // Wait for the group
-    wg.Wait()
+	for _, job := range jobs {
+		jobChan <- job
+	}
+	close(jobChan)
+
+	// Wait for the group
+	wg.Wait()
+
+	// Add every file that made it, and report every one that didn't.
+	// Files skipped because the caller stopped us are reported just once, all together.
+	errs := make([]error, 0)
+	cause := context.Cause(parent)
+	cancelled := false
+	for _, job := range jobs {
+		if job.err != nil && cause != nil && errors.Is(job.err, cause) {
+			cancelled = true
+			continue
+		}
+		if job.err != nil {
+			errs = append(errs, job.err)
+			continue
+		}
 
-	// Add the sheet channel contents
-    for range totalSheetCount {
-        sheet, ok := <-sheetChan
-        if !ok {
-            break
-        }
-		languageFiles.Sheets = append(languageFiles.Sheets, *sheet)
+		if job.fileType == TypeDialogue {
+			languageFiles.Dialogues = append(languageFiles.Dialogues, job.file)
+			continue
+		}
+		languageFiles.Sheets = append(languageFiles.Sheets, job.file)
+	}
+	if cancelled && len(errs) == 0 {
+		return languageFiles, cause
+	}
+	if cancelled {
+		errs = append(errs, cause)
 	}
 
-	return languageFiles, nil
+	return languageFiles, errors.Join(errs...)
 }
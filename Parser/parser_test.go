@@ -1,7 +1,11 @@
 package parser
 
 import (
+	"context"
+	"errors"
 	"os"
+	"reflect"
+	"strconv"
 	"testing"
 )
 
@@ -11,9 +15,9 @@ var FullVerbose bool = false
 func TestParseFile(t *testing.T) {
 	t.Log("Testing parseFile...")
 
-	file, err := os.OpenFile("Data/testOG.csv", os.O_RDONLY, 0444)
+	file, err := os.OpenFile("testdata/Names_Test.csv", os.O_RDONLY, 0444)
 	if err != nil {
-        t.Errorf("File testOG.csv could not be read with error:\n%v", err)
+        t.Fatalf("File Names_Test.csv could not be read with error:\n%v", err)
 	}
     defer file.Close()
 	t.Log("File opened...")
@@ -22,6 +26,9 @@ func TestParseFile(t *testing.T) {
 	if err != nil {
 		t.Errorf("Failed to parse file with error:\n%v", err)
 	}
+	if len(records) == 0 {
+		t.Error("Parsed no records")
+	}
 	t.Log("File parsed...")
 
     if FullVerbose {
@@ -153,3 +160,316 @@ func BenchmarkParseGameFilesConcurrent(b *testing.B) {
         b.Fatal(err)
     }
 }
+
+func TestParseGameFilesDialogues(t *testing.T) {
+	t.Log("Testing dialogue discovery...")
+
+	gamePath := newTestGame(t)
+
+	// One known file gone, one new one added
+	if err := os.Remove(gamePath + "/Dialog/" + KnownDialogueFiles[0] + ".csv"); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, gamePath+"/Dialog/newcomer.csv", "type,flag,expression,English,Japanese,Chinese\r\n0,1,1,Hi,やあ,嗨\r\n")
+
+	for _, parse := range []func(string) (LanguageFiles, error){ParseGameFiles, ParseGameFilesConcurrent} {
+		gameFiles, err := parse(gamePath)
+		if err != nil {
+			t.Fatalf("Failed to parse game files with error:\n%v", err)
+		}
+
+		if len(gameFiles.Dialogues) != len(KnownDialogueFiles) {
+			t.Errorf("Expected %d dialogue files, got %d", len(KnownDialogueFiles), len(gameFiles.Dialogues))
+		}
+		for _, file := range gameFiles.Dialogues {
+			dialogue, ok := file.(*DialogueFile)
+			if !ok {
+				t.Fatalf("Somehow a non-dialogue sheet found its way here???: %+v", file)
+			}
+			if len(dialogue.Strings) == 0 {
+				t.Errorf("Dialogue %v was not parsed", dialogue.File.Name())
+			}
+		}
+
+		if len(gameFiles.Warnings) != 1 {
+			t.Errorf("Expected a warning about the missing dialogue file, got %v", gameFiles.Warnings)
+		}
+	}
+
+	t.Log("Dialogue discovery Passed!")
+}
+
+func TestLoadSheetManifest(t *testing.T) {
+	t.Log("Testing LoadSheetManifest...")
+
+	gamePath := newTestGame(t)
+
+	// A sheet the list doesn't know about, and one the list has but the folder doesn't
+	writeTestFile(t, gamePath+"/Data/Names_Brand_New.csv", "Key,Level,English,Japanese,Chinese\r\n")
+	if err := os.Remove(gamePath + "/Data/Titles_NPC.csv"); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := LoadSheetManifest(gamePath)
+	if err != nil {
+		t.Fatalf("Failed to load manifest with error:\n%v", err)
+	}
+
+	if len(manifest.Sheets) != len(knownSheetNames())-1 {
+		t.Errorf("Expected %d sheets, got %d", len(knownSheetNames())-1, len(manifest.Sheets))
+	}
+	if len(manifest.Missing) != 1 || manifest.Missing[0].Name != "Titles_NPC" {
+		t.Errorf("Expected Titles_NPC to be missing, got %+v", manifest.Missing)
+	}
+	if len(manifest.Unlisted) != 1 || manifest.Unlisted[0].Name != "Names_Brand_New" || manifest.Unlisted[0].Type != TypeName {
+		t.Errorf("Expected Names_Brand_New to be unlisted, got %+v", manifest.Unlisted)
+	}
+	for _, entry := range manifest.Sheets {
+		if entry.Name == "Strings_Dialog" && entry.Type != TypeStringEnum {
+			t.Errorf("Strings_Dialog should be a StringEnum sheet, got %v", entry.Type)
+		}
+	}
+
+	gameFiles, err := ParseGameFiles(gamePath)
+	if err != nil {
+		t.Fatalf("A bad sheet list should not stop parsing, got error:\n%v", err)
+	}
+	if len(gameFiles.Warnings) != 2 {
+		t.Errorf("Expected two warnings, got %v", gameFiles.Warnings)
+	}
+
+	t.Log("LoadSheetManifest Passed!")
+}
+
+func TestParseErrorLocation(t *testing.T) {
+	t.Log("Testing ParseError locations...")
+
+	gamePath := newTestGame(t)
+	path := gamePath + "/Data/Names_Item.csv"
+	broken := "Key,Level,English,Japanese,Chinese\r\n" +
+		"item_a,1,\"Two\r\nlines\",エー,诶\r\n" +
+		"item_b,two,B,ビー,比\r\n"
+	writeTestFile(t, path, broken)
+
+	_, err := ParseGameFiles(gamePath)
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected a ParseError, got %v", err)
+	}
+	if parseErr.Path != path || parseErr.Type != TypeName {
+		t.Errorf("ParseError points at the wrong file: %v", parseErr)
+	}
+	if parseErr.Row != 3 || parseErr.Line != 4 || parseErr.Column != 2 {
+		t.Errorf("ParseError points at the wrong cell: %v", parseErr)
+	}
+	var numErr *strconv.NumError
+	if !errors.As(err, &numErr) {
+		t.Errorf("ParseError should keep its cause, got %v", parseErr.Err)
+	}
+	t.Logf("Got: %v", err)
+
+	writeTestFile(t, path, "Key,Level,English\r\nitem_a,1,\"Never closed\r\n")
+	_, err = ParseGameFiles(gamePath)
+	if !errors.As(err, &parseErr) || parseErr.Row != 2 || parseErr.Column != 3 {
+		t.Errorf("Expected a ParseError at row 2, column 3, got %v", err)
+	}
+
+	// No file at all is still a ParseError
+	err = (&NameSheet{}).Parse()
+	if !errors.As(err, &parseErr) || parseErr.Type != TypeName || !errors.Is(err, ErrNoFile) {
+		t.Errorf("Expected a ParseError for a missing file, got %v", err)
+	}
+
+	t.Log("ParseError locations Passed!")
+}
+
+func TestParseGameFilesConcurrentErrors(t *testing.T) {
+	t.Log("Testing ParseGameFilesConcurrent errors...")
+
+	gamePath := newTestGame(t)
+	for _, name := range []string{"/Data/Names_Item.csv", "/Dialog/frog.csv"} {
+		writeTestFile(t, gamePath+name, "Key,Level,English\r\nbroken,one,Broken\r\n")
+	}
+
+	total := len(knownSheetNames()) + len(KnownDialogueFiles)
+
+	gameFiles, err := ParseGameFilesConcurrent(gamePath)
+	if err == nil {
+		t.Fatalf("Broken files should be reported")
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok || len(joined.Unwrap()) != 2 {
+		t.Fatalf("Expected both broken files to be reported, got %v", err)
+	}
+	for _, fileErr := range joined.Unwrap() {
+		var parseErr *ParseError
+		if !errors.As(fileErr, &parseErr) {
+			t.Errorf("Expected a ParseError, got %v", fileErr)
+		}
+	}
+	if len(gameFiles.Sheets)+len(gameFiles.Dialogues) != total-2 {
+		t.Errorf("Expected every other file to be parsed, got %d of %d", len(gameFiles.Sheets)+len(gameFiles.Dialogues), total-2)
+	}
+	t.Log("Every error reported...")
+
+	gameFiles, err = ParseGameFilesContext(context.Background(), gamePath, ParseOptions{StopOnError: true, Workers: 1})
+	if err == nil {
+		t.Fatalf("Broken files should be reported")
+	}
+	joined, ok = err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("Expected joined errors, got %v", err)
+	}
+	if len(gameFiles.Sheets)+len(gameFiles.Dialogues)+len(joined.Unwrap()) != total {
+		t.Errorf("Every file should be either parsed or reported")
+	}
+
+	// With a single worker, everything after the first broken file is skipped
+	broken := indexOf(knownSheetNames(), "Names_Item")
+	if len(gameFiles.Sheets)+len(gameFiles.Dialogues) != broken {
+		t.Errorf("Expected only the %d files before the broken one to be parsed, got %d", broken, len(gameFiles.Sheets)+len(gameFiles.Dialogues))
+	}
+	stopped := 0
+	for _, fileErr := range joined.Unwrap() {
+		if errors.Is(fileErr, ErrParseStopped) {
+			stopped++
+		}
+	}
+	if stopped != total-broken-1 {
+		t.Errorf("Expected %d files to be skipped with ErrParseStopped, got %d", total-broken-1, stopped)
+	}
+
+	t.Log("ParseGameFilesConcurrent errors Passed!")
+}
+
+// What a translation file holds, minus the open file itself
+func fileContents(t *testing.T, tf TranslationFile) (string, any) {
+	t.Helper()
+
+	switch v := tf.(type) {
+	case *NameSheet:
+		return v.File.Name(), v.Strings
+	case *DescriptionSheet:
+		return v.File.Name(), v.Strings
+	case *TitleSheet:
+		return v.File.Name(), v.Strings
+	case *StringSheet:
+		return v.File.Name(), v.Strings
+	case *StringEnumSheet:
+		return v.File.Name(), v.Strings
+	case *DialogueFile:
+		return v.File.Name(), v.Strings
+	}
+
+	t.Fatalf("Found file of Unknown Type (HOW?): %+v", tf)
+	return "", nil
+}
+
+func checkSameFiles(t *testing.T, expected, got []TranslationFile) {
+	t.Helper()
+
+	if len(expected) != len(got) {
+		t.Fatalf("Expected %d files, got %d", len(expected), len(got))
+	}
+
+	for i := range expected {
+		expectedName, expectedContents := fileContents(t, expected[i])
+		gotName, gotContents := fileContents(t, got[i])
+
+		if expectedName != gotName {
+			t.Errorf("File %d is %v, expected %v", i, gotName, expectedName)
+		}
+		if reflect.TypeOf(expected[i]) != reflect.TypeOf(got[i]) || !reflect.DeepEqual(expectedContents, gotContents) {
+			t.Errorf("File %v differs between loaders", gotName)
+		}
+	}
+}
+
+func TestParseGameFilesLoadersAgree(t *testing.T) {
+	t.Log("Testing both loaders give the same result...")
+
+	gamePath := newTestGame(t)
+
+	sequential, err := ParseGameFiles(gamePath)
+	if err != nil {
+		t.Fatalf("Failed to parse game files with error:\n%v", err)
+	}
+
+	// Once isn't enough to catch goroutines finishing in a different order
+	for range 20 {
+		concurrent, err := ParseGameFilesConcurrent(gamePath)
+		if err != nil {
+			t.Fatalf("Failed to parse game files concurrently with error:\n%v", err)
+		}
+
+		if !reflect.DeepEqual(sequential.Languages.Languages, concurrent.Languages.Languages) {
+			t.Errorf("Languages differ between loaders")
+		}
+		if !reflect.DeepEqual(sequential.Warnings, concurrent.Warnings) {
+			t.Errorf("Warnings differ between loaders")
+		}
+		checkSameFiles(t, sequential.Sheets, concurrent.Sheets)
+		checkSameFiles(t, sequential.Dialogues, concurrent.Dialogues)
+
+		if t.Failed() {
+			break
+		}
+	}
+
+	t.Log("Both loaders give the same result Passed!")
+}
+
+func TestParseGameFilesContext(t *testing.T) {
+	t.Log("Testing ParseGameFilesContext...")
+
+	gamePath := newTestGame(t)
+	total := len(knownSheetNames()) + len(KnownDialogueFiles)
+
+	calls := 0
+	gameFiles, err := ParseGameFilesContext(context.Background(), gamePath, ParseOptions{
+		Workers: 3,
+		Progress: func(done, reportedTotal int) {
+			calls++
+			if done != calls || reportedTotal != total {
+				t.Errorf("Progress reported %d/%d, expected %d/%d", done, reportedTotal, calls, total)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to parse game files with error:\n%v", err)
+	}
+	if calls != total || len(gameFiles.Sheets)+len(gameFiles.Dialogues) != total {
+		t.Errorf("Expected %d files and progress reports, got %d files and %d reports", total, len(gameFiles.Sheets)+len(gameFiles.Dialogues), calls)
+	}
+	t.Log("Progress reported...")
+
+	// Cancel halfway through
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gameFiles, err = ParseGameFilesContext(ctx, gamePath, ParseOptions{
+		Workers: 1,
+		Progress: func(done, total int) {
+			if done == total/2 {
+				cancel()
+			}
+		},
+	})
+	if err != context.Canceled {
+		t.Fatalf("Expected the load to be cancelled with a single error, got %v", err)
+	}
+	parsed := len(gameFiles.Sheets) + len(gameFiles.Dialogues)
+	if parsed != total/2 {
+		t.Errorf("Expected %d files to be parsed before cancelling, got %d", total/2, parsed)
+	}
+
+	// Already cancelled, with a cause of its own
+	stopped := errors.New("stopped by the user")
+	ctx, cancelCause := context.WithCancelCause(context.Background())
+	cancelCause(stopped)
+	if _, err = ParseGameFilesContext(ctx, gamePath, ParseOptions{}); err != stopped {
+		t.Errorf("Expected the cause of the cancellation, got %v", err)
+	}
+
+	t.Log("ParseGameFilesContext Passed!")
+}
@@ -0,0 +1,290 @@
+package parser
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Keeps the backups made during a test out of the repo
+func useTempBackups(t *testing.T) {
+	t.Helper()
+
+	oldBackupDir := BackupDir
+	BackupDir = t.TempDir()
+	StartSnapshot()
+
+	t.Cleanup(func() {
+		BackupDir = oldBackupDir
+		StartSnapshot()
+	})
+}
+
+// Copies a file from testdata into a temporary folder and opens it,
+// so tests can write to it without touching the original
+func openFixture(t *testing.T, name string) *os.File {
+	t.Helper()
+	useTempBackups(t)
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("Fixture %v could not be read with error:\n%v", name, err)
+	}
+
+	path := filepath.Join(t.TempDir(), name)
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+
+	return file
+}
+
+// Writes a file for a test, stopping it if that fails
+func writeTestFile(t *testing.T, path, data string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Writes a small but complete game install into a temporary folder
+func newTestGame(t *testing.T) string {
+	t.Helper()
+	useTempBackups(t)
+
+	gamePath := t.TempDir()
+	if err := os.MkdirAll(gamePath+"/Data", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(gamePath+"/Dialog", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFile(t, gamePath+"/Data/LanguageEnable.csv", "Lang,English,Japanese,Chinese\r\n"+
+		"Desc,English,日本語,中文\r\n"+
+		"enabled,1,1,0\r\n"+
+		"externalFont,0,1,1\r\n"+
+		"font,,NotoSansJP,NotoSansSC\r\n"+
+		"fullWidth,0,1,1\r\n"+
+		"fontSize,55,48,48\r\n"+
+		"offsetAmount,3,0,0\r\n"+
+		"offsetAmountFancy,0,0,0\r\n"+
+		"offsetAmountDialog,0,0,0\r\n"+
+		"characterWidth,40,55,55\r\n"+
+		"characterWidthFancy,56,60,60\r\n"+
+		"characterWidthDialog,40,55,55\r\n")
+
+	sheetList := "Sheet,Purpose\r\nStats_Enemy,stats\r\n"
+	for _, name := range knownSheetNames() {
+		sheetList += name + ",text\r\n"
+	}
+	writeTestFile(t, gamePath+"/Data/"+SheetListName, sheetList)
+	writeTestFile(t, gamePath+"/Data/Stats_Enemy.csv", "Key,HP\r\nwolf,100\r\n")
+
+	keyLevel := func(prefix, name string) {
+		writeTestFile(t, gamePath+"/Data/"+prefix+name+".csv", "Key,Level,English,Japanese,Chinese\r\n"+
+			name+"_a,1,A "+name+",エー,诶\r\n"+
+			",,,,\r\n"+
+			name+"_b,2,\"B, "+name+"\",ビー,比\r\n")
+	}
+	for _, name := range KnownNameFiles {
+		keyLevel("Names_", name)
+	}
+	for _, name := range KnownDescriptionFiles {
+		keyLevel("Descriptions_", name)
+	}
+	for _, name := range KnownTitleFiles {
+		keyLevel("Titles_", name)
+	}
+
+	keyString := func(file, name string) {
+		writeTestFile(t, gamePath+"/Data/"+file+".csv", "Key,English,Japanese,Chinese\r\n"+
+			name+"_a,A "+name+",エー,诶\r\n"+
+			name+"_b,B "+name+",ビー,比\r\n")
+	}
+	keyString("Strings", "Strings")
+	for _, name := range KnownStringFiles {
+		keyString("Strings_"+name, name)
+	}
+	for _, name := range KnownStringEnumFiles {
+		keyString("Strings_"+name, name)
+	}
+
+	for _, name := range KnownDialogueFiles {
+		writeTestFile(t, gamePath+"/Dialog/"+name+".csv", "type,flag,expression,English,Japanese,Chinese\r\n"+
+			"0,007,1,Hello "+name+".,こんにちは。,你好。\r\n"+
+			"1,flag_"+name+",smile,Bye.,じゃあね。,再见。\r\n")
+	}
+
+	return gamePath
+}
+
+// Parses a test game, stopping the test if it doesn't parse
+func parseTestGame(t *testing.T, gamePath string) LanguageFiles {
+	t.Helper()
+
+	gameFiles, err := ParseGameFiles(gamePath)
+	if err != nil {
+		t.Fatalf("Failed to parse game files with error:\n%v", err)
+	}
+
+	return gameFiles
+}
+
+// Writes the test game and parses it
+func openTestGame(t *testing.T) (string, LanguageFiles) {
+	t.Helper()
+
+	gamePath := newTestGame(t)
+	return gamePath, parseTestGame(t, gamePath)
+}
+
+// Parses the file, writes it back, and checks nothing changed
+func testRoundTrip(t *testing.T, file *os.File, sheet TranslationFile) {
+	t.Helper()
+
+	original, err := os.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = sheet.Parse(); err != nil {
+		t.Fatalf("Failed to parse %v with error:\n%v", file.Name(), err)
+	}
+	if err = sheet.Update(); err != nil {
+		t.Fatalf("Failed to update %v with error:\n%v", file.Name(), err)
+	}
+
+	written, err := os.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(original, written) {
+		t.Errorf("Round trip of %v changed the file.\nExpected:\n%q\nGot:\n%q", file.Name(), original, written)
+	}
+}
+
+func TestKeyLevelSheetsRoundTrip(t *testing.T) {
+	t.Log("Testing NameSheet round trip...")
+	file := openFixture(t, "Names_Test.csv")
+	testRoundTrip(t, file, &NameSheet{File: file})
+
+	t.Log("Testing DescriptionSheet round trip...")
+	file = openFixture(t, "Names_Test.csv")
+	testRoundTrip(t, file, &DescriptionSheet{File: file})
+
+	t.Log("Testing TitleSheet round trip...")
+	file = openFixture(t, "Names_Test.csv")
+	testRoundTrip(t, file, &TitleSheet{File: file})
+
+	t.Log("Key/level sheets round trip Passed!")
+}
+
+func TestNameSheetUpdate(t *testing.T) {
+	t.Log("Testing NameSheet update...")
+
+	file := openFixture(t, "Names_Test.csv")
+	sheet := &NameSheet{File: file}
+	if err := sheet.Parse(); err != nil {
+		t.Fatalf("Failed to parse with error:\n%v", err)
+	}
+
+	sheet.Strings[0].Strings[0].String = "Long, Sword"
+	sheet.Strings[1].Level = 8
+
+	if err := sheet.Update(); err != nil {
+		t.Fatalf("Failed to update with error:\n%v", err)
+	}
+
+	written, err := os.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(string(written), "\r\n")
+	if lines[1] != `sword,1,"Long, Sword",剣,剑` {
+		t.Errorf("Edited translation was not written correctly: %q", lines[1])
+	}
+	if lines[2] != `shield,8,"Shield, Big","盾",` {
+		t.Errorf("Edited level was not written correctly: %q", lines[2])
+	}
+	if lines[3] != ",,,," {
+		t.Errorf("Spacer row was not kept: %q", lines[3])
+	}
+
+	t.Log("NameSheet update Passed!")
+}
+
+func TestStringSheetsRoundTrip(t *testing.T) {
+	t.Log("Testing StringSheet round trip...")
+	file := openFixture(t, "Strings_Test.csv")
+	testRoundTrip(t, file, &StringSheet{File: file})
+
+	t.Log("Testing StringEnumSheet round trip...")
+	file = openFixture(t, "Strings_Test.csv")
+	testRoundTrip(t, file, &StringEnumSheet{File: file})
+
+	t.Log("String sheets round trip Passed!")
+}
+
+func TestDialogueFileRoundTrip(t *testing.T) {
+	t.Log("Testing DialogueFile round trip...")
+
+	file := openFixture(t, "Dialog/test.csv")
+	dialogue := &DialogueFile{File: file}
+	testRoundTrip(t, file, dialogue)
+
+	if v, ok := dialogue.Strings[0].FlagScript.(int); !ok || v != 7 {
+		t.Errorf("Expected FlagScript to be the int 7, got %#v", dialogue.Strings[0].FlagScript)
+	}
+	if v, ok := dialogue.Strings[1].FlagScript.(string); !ok || v != "ifFlag_met" {
+		t.Errorf("Expected FlagScript to be the string ifFlag_met, got %#v", dialogue.Strings[1].FlagScript)
+	}
+
+	t.Log("DialogueFile round trip Passed!")
+}
+
+func TestDialogueFileUpdate(t *testing.T) {
+	t.Log("Testing DialogueFile update...")
+
+	file := openFixture(t, "Dialog/test.csv")
+	dialogue := &DialogueFile{File: file}
+	if err := dialogue.Parse(); err != nil {
+		t.Fatalf("Failed to parse with error:\n%v", err)
+	}
+
+	dialogue.Strings[0].Translations[0].String = "Hi, there."
+	dialogue.Strings[1].ExpressionVar0 = 3
+
+	if err := dialogue.Update(); err != nil {
+		t.Fatalf("Failed to update with error:\n%v", err)
+	}
+
+	written, err := os.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(string(written), "\r\n")
+	if lines[1] != `0,007,1,"Hi, there.",こんにちは。,你好。` {
+		t.Errorf("Edited translation was not written correctly: %q", lines[1])
+	}
+	if lines[2] != `1,ifFlag_met,3,"Oh, it's you!",またあなたね！,又是你！` {
+		t.Errorf("Edited expression was not written correctly: %q", lines[2])
+	}
+
+	t.Log("DialogueFile update Passed!")
+}
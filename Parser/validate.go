@@ -0,0 +1,421 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// How bad an Issue is
+type Severity uint8
+
+const (
+	// Worth knowing, the game doesn't mind
+	SeverityInfo Severity = iota
+
+	// Probably a mistake, text may be missing or ignored
+	SeverityWarning
+
+	// The game or this parser will get it wrong
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+
+	return fmt.Sprintf("Severity(%d)", uint8(s))
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(text []byte) error {
+	for _, severity := range []Severity{SeverityInfo, SeverityWarning, SeverityError} {
+		if string(text) == severity.String() {
+			*s = severity
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Unknown severity %q", text)
+}
+
+// A problem found by Validate
+type Issue struct {
+	Severity Severity `json:"severity"`
+
+	// SheetID of the file, empty if the problem isn't in any one file
+	Sheet string `json:"sheet,omitempty"`
+
+	// Record of the file, starting at 1 with the header, like in ParseError. 0 if the problem is with the whole file.
+	Row int `json:"row,omitempty"`
+
+	// Line of the file the record starts at, 0 if unknown
+	Line int `json:"line,omitempty"`
+
+	// Field of the record, starting at 1. 0 if the problem is with the whole record.
+	Column int `json:"column,omitempty"`
+
+	Key string `json:"key,omitempty"`
+
+	// Language of the cell, for problems with a single translation
+	Language string `json:"language,omitempty"`
+
+	// What kind of problem it is, for the ones tools may want to tell apart
+	Code string `json:"code,omitempty"`
+
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	location := i.Sheet
+	if i.Row > 0 {
+		location += fmt.Sprintf(", row %d", i.Row)
+	}
+	if i.Line > 0 && i.Line != i.Row {
+		location += fmt.Sprintf(" (line %d)", i.Line)
+	}
+	if i.Column > 0 {
+		location += fmt.Sprintf(", column %d", i.Column)
+	}
+	if i.Key != "" {
+		location += fmt.Sprintf(", key %v", i.Key)
+	}
+	if i.Language != "" {
+		location += fmt.Sprintf(", %v", i.Language)
+	}
+	if location != "" {
+		location += ": "
+	}
+
+	return fmt.Sprintf("%v: %v%v", i.Severity, location, i.Message)
+}
+
+// Whether any of the issues is an error
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Checks the structure of every parsed file:
+// LanguageEnable.csv itself, keys being unique within a sheet, rows having as many cells as the header,
+// sheets having the same language columns as LanguageEnable.csv
+// and rows without a key not having any text (the game skips them).
+//
+// It looks at what is in memory, so run it before saving.
+// Files that didn't parse at all, like sheets with a Level that isn't a number, are reported by ValidateGame,
+// which still checks the rest of a sheet whose only problem is its Levels.
+func Validate(lfs LanguageFiles) []Issue {
+	issues := make([]Issue, 0)
+
+	for _, err := range flattenErrors(lfs.Languages.Validate()) {
+		issue := Issue{Severity: SeverityError, Message: err.Error()}
+		if lfs.Languages.File != nil {
+			issue.Sheet = sheetPathID(lfs.Languages.File.Name())
+		}
+		issues = append(issues, issue)
+	}
+
+	for _, tf := range append(append([]TranslationFile(nil), lfs.Sheets...), lfs.Dialogues...) {
+		issues = append(issues, validateFile(tf, lfs.Languages.Names())...)
+	}
+
+	return issues
+}
+
+// Parses every file of a game install and validates them.
+// Files that fail to parse are reported as errors instead of stopping everything,
+// and the loader's warnings are reported as warnings.
+func ValidateGame(gamePath string) ([]Issue, error) {
+	lfs, err := ParseGameFilesContext(context.Background(), gamePath, ParseOptions{})
+	if lfs.Languages.layout == nil {
+		// Without LanguageEnable.csv there is nothing to compare against
+		return nil, err
+	}
+
+	issues := make([]Issue, 0)
+	for _, warning := range lfs.Warnings {
+		issues = append(issues, Issue{Severity: SeverityWarning, Message: warning.Error()})
+	}
+
+	for _, err := range flattenErrors(err) {
+		issue := Issue{Severity: SeverityError, Message: err.Error()}
+
+		var parseErr *ParseError
+		if errors.As(err, &parseErr) {
+			// A bad Level doesn't keep the rest of the sheet from being checked,
+			// only Name, Description and Title sheets have them
+			var numErr *strconv.NumError
+			keyLevel := parseErr.Type == TypeName || parseErr.Type == TypeDescription || parseErr.Type == TypeTitle
+			if keyLevel && errors.As(err, &numErr) {
+				if levelIssues, ok := validateBadLevels(parseErr.Path, parseErr.Type, lfs.Languages.Names()); ok {
+					issues = append(issues, levelIssues...)
+					continue
+				}
+			}
+			issue.Sheet = sheetPathID(parseErr.Path)
+			issue.Row, issue.Line, issue.Column = parseErr.Row, parseErr.Line, parseErr.Column
+			issue.Message = parseErr.Err.Error()
+		}
+
+		issues = append(issues, issue)
+	}
+
+	return append(issues, Validate(lfs)...), nil
+}
+
+// Validates a sheet that failed to parse because of Levels that aren't numbers.
+// Every such cell is reported, and read as 0 so the rest of the sheet can still be checked.
+// Returns false if the sheet can't be parsed even then.
+func validateBadLevels(path string, fileType FileType, languages []string) ([]Issue, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	defer file.Close()
+
+	records, layout, err := parseFileLayout(file)
+	if err != nil {
+		return nil, false
+	}
+
+	sheet := sheetPathID(path)
+	issues := make([]Issue, 0)
+	for r := 1; r < len(records); r++ {
+		if records[r][0] == "" || len(records[r]) < 2 {
+			continue
+		}
+		if _, err := strconv.Atoi(records[r][1]); err == nil {
+			continue
+		}
+
+		issues = append(issues, Issue{
+			Severity: SeverityError,
+			Sheet:    sheet,
+			Row:      r + 1,
+			Line:     layout.lines[r],
+			Column:   2,
+			Key:      records[r][0],
+			Message:  fmt.Sprintf("Level %q is not a number", records[r][1]),
+		})
+		records[r][1] = "0"
+	}
+
+	rows := make([]KeyLevelStrings, 0)
+	if err = ParseKeyLevelStrings(&rows, records); err != nil {
+		return nil, false
+	}
+
+	var tf TranslationFile
+	switch fileType {
+	case TypeName:
+		tf = &NameSheet{File: file, Strings: rows, layout: layout}
+	case TypeDescription:
+		tf = &DescriptionSheet{File: file, Strings: rows, layout: layout}
+	case TypeTitle:
+		tf = &TitleSheet{File: file, Strings: rows, layout: layout}
+	default:
+		return nil, false
+	}
+
+	return append(issues, validateFile(tf, languages)...), true
+}
+
+// An issue located at the cell of an entry in a language column (starting at 0)
+func cellIssue(v *sheetView, entry *Entry, column int) Issue {
+	issue := Issue{
+		Sheet:    entry.Sheet,
+		Row:      entry.Row + 2,
+		Column:   v.fixed + column + 1,
+		Key:      entry.Key,
+		Language: entry.Languages[column],
+	}
+	if entry.Row+1 < len((*v.layout).lines) {
+		issue.Line = (*v.layout).lines[entry.Row+1]
+	}
+
+	return issue
+}
+
+// Every error inside errors.Join, however deep
+func flattenErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+
+	errs := make([]error, 0)
+	for _, inner := range joined.Unwrap() {
+		errs = append(errs, flattenErrors(inner)...)
+	}
+
+	return errs
+}
+
+// A row of a file, for validating
+type validatedRow struct {
+	key   string
+	cells int
+
+	// Whether the row is a spacer the game skips
+	spacer bool
+}
+
+func validateFile(tf TranslationFile, languages []string) []Issue {
+	sheet := SheetID(tf)
+	v, err := viewSheet(tf)
+	if err == nil {
+		err = v.check()
+	}
+	if err != nil {
+		return []Issue{{Severity: SeverityError, Sheet: sheet, Message: err.Error()}}
+	}
+
+	layout := *v.layout
+	issues := make([]Issue, 0)
+	report := func(severity Severity, r, column int, key, message string) {
+		issue := Issue{Severity: severity, Sheet: sheet, Row: r + 1, Column: column, Key: key, Message: message}
+		if r < len(layout.lines) {
+			issue.Line = layout.lines[r]
+		}
+		issues = append(issues, issue)
+	}
+
+	issues = append(issues, validateColumns(sheet, v.languages(), languages)...)
+
+	rows := make([]validatedRow, 0)
+	switch f := tf.(type) {
+	case *NameSheet:
+		rows = validatedKeyLevelRows(f.Strings)
+	case *DescriptionSheet:
+		rows = validatedKeyLevelRows(f.Strings)
+	case *TitleSheet:
+		rows = validatedKeyLevelRows(f.Strings)
+	case *StringSheet:
+		rows = validatedKeyRows(f.Strings)
+	case *StringEnumSheet:
+		rows = validatedKeyRows(f.Strings)
+	case *DialogueFile:
+		for _, row := range f.Strings {
+			spacer := row.FlagScript == nil && row.ExpressionVar0 == nil
+			rows = append(rows, validatedRow{"", 3 + len(row.Translations), spacer})
+		}
+	}
+
+	header := len(layout.values[0])
+	seen := make(map[string]int)
+
+	for i, row := range rows {
+		// Rows come right after the header
+		r := i + 1
+
+		if row.spacer {
+			// The game skips these, so any text in them is lost
+			if r < len(layout.values) && hasText(layout.values[r]) {
+				report(SeverityWarning, r, 1, "", "Row has text but the game skips it, it has no key")
+			}
+			continue
+		}
+
+		if row.key != "" {
+			if first, ok := seen[row.key]; ok {
+				report(SeverityError, r, 1, row.key, fmt.Sprintf("Key is already used on row %d", first+1))
+			} else {
+				seen[row.key] = r
+			}
+		}
+
+		if row.cells != header {
+			report(SeverityWarning, r, 0, row.key, fmt.Sprintf("Row has %d cells but the header has %d", row.cells, header))
+		}
+
+	}
+
+	return issues
+}
+
+func validatedKeyLevelRows(sheet []KeyLevelStrings) []validatedRow {
+	rows := make([]validatedRow, len(sheet))
+	for i, row := range sheet {
+		rows[i] = validatedRow{row.Key, 2 + len(row.Strings), row.Key == ""}
+	}
+
+	return rows
+}
+
+func validatedKeyRows(sheet []KeyStrings) []validatedRow {
+	rows := make([]validatedRow, len(sheet))
+	for i, row := range sheet {
+		rows[i] = validatedRow{row.Key, 1 + len(row.Strings), row.Key == ""}
+	}
+
+	return rows
+}
+
+// Compares the language columns of a file with LanguageEnable.csv
+func validateColumns(sheet string, columns, languages []string) []Issue {
+	issues := make([]Issue, 0)
+	report := func(severity Severity, message string) {
+		issues = append(issues, Issue{Severity: severity, Sheet: sheet, Row: 1, Message: message})
+	}
+
+	found := make(map[string]bool)
+	for _, column := range columns {
+		if column == "" {
+			report(SeverityError, "A language column has no name")
+			continue
+		}
+		if found[column] {
+			report(SeverityError, fmt.Sprintf("Language %v has more than one column", column))
+		}
+		found[column] = true
+
+		if indexOf(languages, column) < 0 {
+			report(SeverityWarning, fmt.Sprintf("Language %v is not in LanguageEnable.csv", column))
+		}
+	}
+
+	missing := make([]string, 0)
+	for _, language := range languages {
+		if !found[language] {
+			missing = append(missing, language)
+		}
+	}
+	if len(missing) > 0 {
+		report(SeverityError, fmt.Sprintf("Missing columns for %v", strings.Join(missing, ", ")))
+	} else if len(columns) == len(languages) && strings.Join(columns, ",") != strings.Join(languages, ",") {
+		report(SeverityInfo, "Language columns are not in the same order as LanguageEnable.csv")
+	}
+
+	return issues
+}
+
+func hasText(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,117 @@
+package parser
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// Finds the issue about a row of a sheet
+func findIssue(issues []Issue, sheet string, row int) *Issue {
+	for i := range issues {
+		if issues[i].Sheet == sheet && issues[i].Row == row {
+			return &issues[i]
+		}
+	}
+
+	return nil
+}
+
+func TestValidateGame(t *testing.T) {
+	t.Log("Testing ValidateGame...")
+
+	gamePath := newTestGame(t)
+	writeTestFile(t, gamePath+"/Data/Names_Item.csv", "Key,Level,English,Japanese,Chinese\r\nItem_a,1,A,エー,诶\r\nItem_b,1,B,ビー,比\r\nItem_a,2,A again,エー,诶\r\n")
+	writeTestFile(t, gamePath+"/Data/Strings_Menu.csv", "Key,English,Japanese,Chinese\r\nMenu_a,A,エー\r\n,Lost,,\r\n,,,\r\n")
+	writeTestFile(t, gamePath+"/Data/Descriptions_Potion.csv", "Key,Level,English,Japanese,Chinese\r\nPotion_a,one,A,エー,诶\r\nPotion_b,2,B,ビー,比\r\nPotion_a,3,A again,エー,诶\r\n")
+	writeTestFile(t, gamePath+"/Data/Strings_Intro.csv", "Key,English,Japanese\r\nIntro_a,A,エー\r\n")
+	writeTestFile(t, gamePath+"/Dialog/frog.csv", "type,flag,expression,Japanese,English,Chinese\r\n0,1,1,こんにちは。,Hello.,你好。\r\n")
+	// Dialogue has no levels, a bad number in it is just a broken file
+	writeTestFile(t, gamePath+"/Dialog/birds.csv", "type,flag,expression,English,Japanese,Chinese\r\nx,1,1,Tweet.,,\r\n")
+
+	issues, err := ValidateGame(gamePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, issue := range issues {
+		t.Log(issue)
+	}
+
+	expected := []struct {
+		sheet    string
+		row      int
+		severity Severity
+		key      string
+	}{
+		{"Data/Names_Item.csv", 4, SeverityError, "Item_a"},
+		{"Data/Strings_Menu.csv", 2, SeverityWarning, "Menu_a"},
+		{"Data/Strings_Menu.csv", 3, SeverityWarning, ""},
+		{"Data/Descriptions_Potion.csv", 2, SeverityError, "Potion_a"},
+		{"Data/Descriptions_Potion.csv", 4, SeverityError, "Potion_a"},
+		{"Data/Strings_Intro.csv", 1, SeverityError, ""},
+		{"Dialog/frog.csv", 1, SeverityInfo, ""},
+		{"Dialog/birds.csv", 2, SeverityError, ""},
+	}
+	for _, e := range expected {
+		issue := findIssue(issues, e.sheet, e.row)
+		if issue == nil {
+			t.Errorf("Expected an issue on row %d of %v", e.row, e.sheet)
+			continue
+		}
+		if issue.Severity != e.severity || issue.Key != e.key {
+			t.Errorf("Expected a %v about %q, got %v", e.severity, e.key, issue)
+		}
+	}
+	if issue := findIssue(issues, "Dialog/birds.csv", 2); issue != nil && (issue.Column != 1 || strings.Contains(issue.Message, "Level")) {
+		t.Errorf("Expected the bad type of the dialogue to be reported as it is, got %v", issue)
+	}
+	if issue := findIssue(issues, "Data/Strings_Menu.csv", 4); issue != nil {
+		t.Errorf("Empty spacer row was reported: %v", issue)
+	}
+	if len(issues) != len(expected) {
+		t.Errorf("Expected %d issues, got %d", len(expected), len(issues))
+	}
+	if !HasErrors(issues) {
+		t.Error("HasErrors missed the errors")
+	}
+	t.Log("Broken files reported...")
+
+	data, err := json.Marshal(issues[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Issue
+	if err = json.Unmarshal(data, &decoded); err != nil || decoded != issues[0] {
+		t.Errorf("Issue didn't survive JSON: %s", data)
+	}
+
+	t.Log("ValidateGame Passed!")
+}
+
+func TestValidateInMemory(t *testing.T) {
+	t.Log("Testing Validate on edited files...")
+
+	_, gameFiles := openTestGame(t)
+
+	if issues := Validate(gameFiles); len(issues) != 0 {
+		t.Fatalf("Expected a clean game, got %v", issues)
+	}
+
+	// A key changed to one that already exists
+	names := gameFiles.Sheets[0].(*NameSheet)
+	names.Strings[2].Key = names.Strings[0].Key
+	gameFiles.Languages.Languages[1].FontSize = 0
+
+	issues := Validate(gameFiles)
+	if len(issues) != 2 {
+		t.Fatalf("Expected 2 issues, got %v", issues)
+	}
+	if issues[0].Sheet != "Data/LanguageEnable.csv" || issues[0].Severity != SeverityError {
+		t.Errorf("Expected the font size to be reported, got %v", issues[0])
+	}
+	if issues[1].Sheet != "Data/Names_HBS.csv" || issues[1].Row != 4 || issues[1].Key != "HBS_a" {
+		t.Errorf("Expected the duplicate key to be reported, got %v", issues[1])
+	}
+
+	t.Log("Validate Passed!")
+}
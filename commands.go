@@ -0,0 +1,469 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	exchange "github.com/Diamon0/rns-babel/Exchange"
+	parser "github.com/Diamon0/rns-babel/Parser"
+)
+
+// Commands that run instead of the terminal UI, as in `rns-babel <command> [flags] <game folder>`
+type command struct {
+	description string
+	run         func(args []string) error
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"charset":       {"Lists the characters every language uses, for making fonts", charsetCommand},
+		"po-export":     {"Exports a gettext POT template and a PO file per language", poExportCommand},
+		"po-import":     {"Imports translated PO files into the game files", poImportCommand},
+		"xliff-export":  {"Exports an XLIFF 2.0 file per language", xliffExportCommand},
+		"xliff-import":  {"Imports translated XLIFF files into the game files", xliffImportCommand},
+		"bundle-export": {"Exports a JSON or YAML bundle per language", bundleExportCommand},
+		"bundle-import": {"Imports translated JSON or YAML bundles into the game files", bundleImportCommand},
+		"xlsx-export":   {"Exports every sheet and dialogue into one XLSX workbook", xlsxExportCommand},
+		"xlsx-import":   {"Imports an edited XLSX workbook into the game files", xlsxImportCommand},
+		"tmx-export":    {"Exports a TMX translation memory of every language pair", tmxExportCommand},
+		"tmx-import":    {"Adds TMX files to the local translation memory", tmxImportCommand},
+		"tm-search":     {"Looks up translations in the local translation memory", tmSearchCommand},
+		"help":          {"Shows this", helpCommand},
+	}
+}
+
+// Runs the command in args and returns the exit code
+func runCommand(args []string) int {
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %v\n\n", args[0])
+		helpCommand(nil)
+		return 2
+	}
+
+	// Whatever the command changes can be rolled back on its own
+	parser.StartSnapshot()
+	if err := cmd.run(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	return 0
+}
+
+func helpCommand(args []string) error {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "Usage: rns-babel [command] [flags] <game folder>")
+	fmt.Fprintln(os.Stderr, "Without a command, the terminal UI is started.")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-14v %v\n", name, commands[name].description)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun rns-babel <command> -h for its flags.")
+
+	return nil
+}
+
+// Parses the flags of a command and loads the game folder that follows them.
+// Commands that take files after the game folder say so with files, and get them back.
+func loadGame(flags *flag.FlagSet, args []string, files bool) (parser.LanguageFiles, []string, error) {
+	if err := flags.Parse(args); err != nil {
+		return parser.LanguageFiles{}, nil, err
+	}
+	if flags.NArg() < 1 || (flags.NArg() > 1) != files {
+		flags.Usage()
+		if files {
+			return parser.LanguageFiles{}, nil, errors.New("Expected a game folder followed by files")
+		}
+		return parser.LanguageFiles{}, nil, errors.New("Expected exactly one game folder")
+	}
+
+	gameFiles, err := parser.ParseGameFilesConcurrent(flags.Arg(0))
+	for _, warning := range gameFiles.Warnings {
+		fmt.Fprintln(os.Stderr, "Warning:", warning)
+	}
+
+	return gameFiles, flags.Args()[1:], err
+}
+
+func charsetCommand(args []string) error {
+	flags := flag.NewFlagSet("charset", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "Output JSON instead of plain text")
+	languages := flags.String("lang", "", "Only these languages, separated by commas")
+	subsetDir := flags.String("subset", "", "Also write a code point list per language into this folder, for font subsetters (e.g. pyftsubset --unicodes-file)")
+
+	gameFiles, _, err := loadGame(flags, args, false)
+	if err != nil {
+		return err
+	}
+
+	sets := parser.CharacterSets(gameFiles)
+	if *languages != "" {
+		wanted := strings.Split(*languages, ",")
+		filtered := make([]parser.CharacterSet, 0, len(wanted))
+		for _, name := range wanted {
+			found := false
+			for _, set := range sets {
+				if set.Language == strings.TrimSpace(name) {
+					filtered = append(filtered, set)
+					found = true
+				}
+			}
+			if !found {
+				return fmt.Errorf("Language %v does not exist", name)
+			}
+		}
+		sets = filtered
+	}
+
+	if *subsetDir != "" {
+		if err = os.MkdirAll(*subsetDir, 0755); err != nil {
+			return err
+		}
+		for _, set := range sets {
+			if err = os.WriteFile(filepath.Join(*subsetDir, set.Language+".unicodes"), []byte(set.SubsetList()), 0644); err != nil {
+				return err
+			}
+		}
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "\t")
+		return encoder.Encode(sets)
+	}
+
+	return parser.WriteCharacterSets(os.Stdout, sets)
+}
+
+func poExportCommand(args []string) error {
+	flags := flag.NewFlagSet("po-export", flag.ContinueOnError)
+	reference := flags.String("ref", "", "Language the msgids come from, the first one in LanguageEnable.csv by default")
+	out := flags.String("out", "po", "Folder to write template.pot and the PO files into")
+
+	gameFiles, _, err := loadGame(flags, args, false)
+	if err != nil {
+		return err
+	}
+
+	return exchange.ExportPOFiles(*out, gameFiles, exchange.POOptions{Reference: *reference})
+}
+
+func poImportCommand(args []string) error {
+	flags := flag.NewFlagSet("po-import", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: rns-babel po-import [flags] <game folder> <PO files...>")
+		flags.PrintDefaults()
+	}
+	lang := flags.String("lang", "", "Language to import into, taken from each file's header by default")
+	fuzzy := flags.Bool("fuzzy", false, "Also import entries marked as fuzzy")
+	changed := flags.Bool("changed", false, "Also import entries whose source text changed since they were exported, except dialogue lines")
+	dryRun := flags.Bool("dry-run", false, "Only report what would change")
+
+	gameFiles, files, err := loadGame(flags, args, true)
+	if err != nil {
+		return err
+	}
+
+	opts := exchange.POImportOptions{
+		Language:       *lang,
+		IncludeFuzzy:   *fuzzy,
+		IncludeChanged: *changed,
+		DryRun:         *dryRun,
+	}
+
+	for _, path := range files {
+		report, err := exchange.ImportPOFile(path, &gameFiles, opts)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%v (%v): %d updated, %d unchanged, %d untranslated\n", path, report.Language, len(report.Updated), report.Unchanged, report.Untranslated)
+		for _, entry := range report.Orphans {
+			fmt.Printf("  Orphan, the key no longer exists: %v\n", entry.Context)
+		}
+		for _, entry := range report.Fuzzy {
+			fmt.Printf("  Fuzzy: %v\n", entry.Context)
+		}
+		for _, entry := range report.SourceChanged {
+			fmt.Printf("  Source text changed since it was exported: %v\n", entry.Context)
+		}
+		for _, sheet := range report.Saved {
+			fmt.Printf("  Saved %v\n", sheet)
+		}
+	}
+
+	return nil
+}
+
+func xliffExportCommand(args []string) error {
+	flags := flag.NewFlagSet("xliff-export", flag.ContinueOnError)
+	reference := flags.String("ref", "", "Language the sources come from, the first one in LanguageEnable.csv by default")
+	out := flags.String("out", "xliff", "Folder to write the XLIFF files into")
+
+	gameFiles, _, err := loadGame(flags, args, false)
+	if err != nil {
+		return err
+	}
+
+	return exchange.ExportXLIFFFiles(*out, gameFiles, exchange.XLIFFOptions{Reference: *reference})
+}
+
+func xliffImportCommand(args []string) error {
+	flags := flag.NewFlagSet("xliff-import", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: rns-babel xliff-import [flags] <game folder> <XLIFF files...>")
+		flags.PrintDefaults()
+	}
+	skipDrifted := flags.Bool("skip-drifted", false, "Import the rest of a file when the source text of some units changed, instead of refusing it")
+	dryRun := flags.Bool("dry-run", false, "Only report what would change")
+
+	gameFiles, files, err := loadGame(flags, args, true)
+	if err != nil {
+		return err
+	}
+
+	opts := exchange.XLIFFImportOptions{
+		SkipDrifted: *skipDrifted,
+		DryRun:      *dryRun,
+	}
+
+	for _, path := range files {
+		report, err := exchange.ImportXLIFFFile(path, &gameFiles, opts)
+		if report != nil {
+			fmt.Printf("%v (%v): %d updated, %d unchanged, %d untranslated\n", path, report.Language, len(report.Updated), report.Unchanged, report.Untranslated)
+			for _, ref := range report.Orphans {
+				fmt.Printf("  Orphan, the key no longer exists: %v\n", exchange.Context(ref.Sheet, ref.Unit.Name))
+			}
+			for _, ref := range report.Drifted {
+				fmt.Printf("  Source text changed since it was exported: %v\n", exchange.Context(ref.Sheet, ref.Unit.Name))
+			}
+			for _, sheet := range report.Saved {
+				fmt.Printf("  Saved %v\n", sheet)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func bundleExportCommand(args []string) error {
+	flags := flag.NewFlagSet("bundle-export", flag.ContinueOnError)
+	format := flags.String("format", "json", "json or yaml")
+	reference := flags.String("ref", "", "Language the sources come from, the first one in LanguageEnable.csv by default")
+	out := flags.String("out", "bundles", "Folder to write the bundles into")
+
+	gameFiles, _, err := loadGame(flags, args, false)
+	if err != nil {
+		return err
+	}
+
+	bundleFormat := exchange.BundleFormat(strings.ToLower(*format))
+	if bundleFormat != exchange.BundleJSON && bundleFormat != exchange.BundleYAML {
+		return fmt.Errorf("Unknown format %v, expected json or yaml", *format)
+	}
+
+	return exchange.ExportBundleFiles(*out, gameFiles, bundleFormat, exchange.BundleOptions{Reference: *reference})
+}
+
+func bundleImportCommand(args []string) error {
+	flags := flag.NewFlagSet("bundle-import", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: rns-babel bundle-import [flags] <game folder> <JSON or YAML files...>")
+		flags.PrintDefaults()
+	}
+	changed := flags.Bool("changed", false, "Also import entries whose source text changed since they were exported, except dialogue lines")
+	dryRun := flags.Bool("dry-run", false, "Only report what would change")
+
+	gameFiles, files, err := loadGame(flags, args, true)
+	if err != nil {
+		return err
+	}
+
+	opts := exchange.BundleImportOptions{
+		IncludeChanged: *changed,
+		DryRun:         *dryRun,
+	}
+
+	for _, path := range files {
+		report, err := exchange.ImportBundleFile(path, &gameFiles, opts)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%v (%v): %d updated, %d unchanged, %d untranslated\n", path, report.Language, len(report.Updated), report.Unchanged, report.Untranslated)
+		for _, context := range report.Orphans {
+			fmt.Printf("  Orphan, the key no longer exists: %v\n", context)
+		}
+		for _, context := range report.SourceChanged {
+			fmt.Printf("  Source text changed since it was exported: %v\n", context)
+		}
+		for _, sheet := range report.Saved {
+			fmt.Printf("  Saved %v\n", sheet)
+		}
+	}
+
+	return nil
+}
+
+func xlsxExportCommand(args []string) error {
+	flags := flag.NewFlagSet("xlsx-export", flag.ContinueOnError)
+	out := flags.String("out", "translations.xlsx", "File to write the workbook into")
+
+	gameFiles, _, err := loadGame(flags, args, false)
+	if err != nil {
+		return err
+	}
+
+	return exchange.ExportWorkbook(*out, gameFiles)
+}
+
+func xlsxImportCommand(args []string) error {
+	flags := flag.NewFlagSet("xlsx-import", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: rns-babel xlsx-import [flags] <game folder> <XLSX files...>")
+		flags.PrintDefaults()
+	}
+	dryRun := flags.Bool("dry-run", false, "Only report what would change")
+
+	gameFiles, files, err := loadGame(flags, args, true)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range files {
+		report, err := exchange.ImportWorkbookFile(path, &gameFiles, exchange.WorkbookImportOptions{DryRun: *dryRun})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%v: %d cells in %d rows updated, %d rows unchanged\n", path, report.Cells, len(report.Updated), report.Unchanged)
+		for _, context := range report.Orphans {
+			fmt.Printf("  Orphan, the key no longer exists: %v\n", context)
+		}
+		for _, context := range report.Moved {
+			fmt.Printf("  Row was moved in the workbook, left alone: %v\n", context)
+		}
+		for _, context := range report.Drifted {
+			fmt.Printf("  Dialogue line's source text changed since the export, left alone: %v\n", context)
+		}
+		for _, context := range report.Conflicts {
+			fmt.Printf("  Changed in the game files since the export too, left alone: %v\n", context)
+		}
+		for _, sheet := range report.Saved {
+			fmt.Printf("  Saved %v\n", sheet)
+		}
+	}
+
+	return nil
+}
+
+func tmxExportCommand(args []string) error {
+	flags := flag.NewFlagSet("tmx-export", flag.ContinueOnError)
+	out := flags.String("out", "translations.tmx", "File to write the translation memory into")
+
+	gameFiles, _, err := loadGame(flags, args, false)
+	if err != nil {
+		return err
+	}
+
+	return exchange.ExportTMXFile(*out, gameFiles)
+}
+
+func tmxImportCommand(args []string) error {
+	flags := flag.NewFlagSet("tmx-import", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: rns-babel tmx-import [flags] <TMX files...>")
+		flags.PrintDefaults()
+	}
+	store := flags.String("tm", "memory.tmx", "The local translation memory, created if it doesn't exist")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 1 {
+		flags.Usage()
+		return errors.New("Expected TMX files")
+	}
+
+	tm, err := exchange.LoadTranslationMemory(*store)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range flags.Args() {
+		added, err := exchange.ImportTMXFile(path, tm)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%v: %d new units\n", path, added)
+	}
+
+	if err = tm.Save(*store); err != nil {
+		return err
+	}
+	fmt.Printf("%v has %d units\n", *store, len(tm.Units))
+
+	return nil
+}
+
+func tmSearchCommand(args []string) error {
+	flags := flag.NewFlagSet("tm-search", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: rns-babel tm-search [flags] <text>")
+		flags.PrintDefaults()
+	}
+	store := flags.String("tm", "memory.tmx", "The local translation memory")
+	from := flags.String("from", "English", "Language of the text, as a name or a code")
+	to := flags.String("to", "", "Language to find translations in, as a name or a code")
+	minScore := flags.Float64("min", 0.75, "How alike texts have to be to the one searched for, from 0 to 1")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 1 || *to == "" {
+		flags.Usage()
+		return errors.New("Expected -to and a text")
+	}
+
+	tm, err := exchange.LoadTranslationMemory(*store)
+	if err != nil {
+		return err
+	}
+
+	matches, err := tm.Search(*from, strings.Join(flags.Args(), " "), *to, *minScore)
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		fmt.Println("Nothing found")
+	}
+	for _, match := range matches {
+		fmt.Printf("%3.0f%%  %q -> %q", match.Score*100, match.Source, match.Target)
+		if match.Sheet != "" {
+			fmt.Printf("  (%v)", exchange.Context(match.Sheet, match.Key))
+		}
+		fmt.Println()
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"math"
+	"os"
 	"os/exec"
 	logger "github.com/Diamon0/rns-babel/Logger"
 	webui "github.com/Diamon0/rns-babel/WebUI"
@@ -175,6 +176,11 @@ func drawBox(s tcell.Screen, x1, y1, x2, y2 int, boxStyle, borderStyle, textStyl
 }
 
 func main() {
+	// Commands skip the terminal UI entirely
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
 	var isServerOn atomic.Bool
     // TODO: Change this "signaler" into a context with cancel, "make"s more sense (hehe, get it? because make)
 	signaler := make(chan int8)